@@ -0,0 +1,294 @@
+package blackboard
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Key identifies a blackboard entry holding a T.
+type Key[T any] struct {
+	name string
+}
+
+// NewKey returns the key for the entry called name.
+func NewKey[T any](name string) Key[T] {
+	return Key[T]{name: name}
+}
+
+func (k Key[T]) Name() string {
+	return k.name
+}
+
+// Blackboard is a set of named values shared by the nodes of a tree.
+// Blackboards can be scoped: a scope reads the entries of its parent that
+// it doesn't hold itself, and keeps its own writes, except for keys
+// remapped to an entry of the parent. A Blackboard is safe for concurrent
+// use.
+type Blackboard struct {
+	mu     sync.RWMutex
+	values map[string]any
+	// versions stamps the last change to each key of this blackboard, see
+	// version.
+	versions map[string]uint64
+	parent   *Blackboard
+	// remap maps keys of this scope to keys of the parent.
+	remap map[string]string
+
+	// tx is set for transactions, see Begin.
+	tx *txState
+	// commit is only used on the topmost blackboard of a hierarchy.
+	// Commit holds it while checking and applying a transaction, and
+	// every other write read-locks it, so a commit can't interleave with
+	// changes to the entries it checked.
+	commit sync.RWMutex
+}
+
+// New returns an empty blackboard.
+func New() *Blackboard {
+	return &Blackboard{values: map[string]any{}, versions: map[string]uint64{}}
+}
+
+// Scope returns a child blackboard of b. remap maps keys of the child to
+// keys of b: reads and writes of a remapped key go to b's entry. Other
+// keys fall back to b for reading, but are written to the child only.
+func (b *Blackboard) Scope(remap map[string]string) *Blackboard {
+	return &Blackboard{
+		values:   map[string]any{},
+		versions: map[string]uint64{},
+		parent:   b,
+		remap:    maps.Clone(remap),
+	}
+}
+
+// Parent returns the blackboard b is a scope of, or nil.
+func (b *Blackboard) Parent() *Blackboard {
+	return b.parent
+}
+
+// top returns the topmost ancestor of b, or b itself.
+func (b *Blackboard) top() *Blackboard {
+	for b.parent != nil {
+		b = b.parent
+	}
+	return b
+}
+
+// Get returns the value of key, and whether it is set to a T.
+func Get[T any](b *Blackboard, key Key[T]) (T, bool) {
+	value, ok := b.get(key.name)
+	t, isT := value.(T)
+	return t, ok && isT
+}
+
+// GetOr returns the value of key, or def if it is not set to a T.
+func GetOr[T any](b *Blackboard, key Key[T], def T) T {
+	if value, ok := Get(b, key); ok {
+		return value
+	}
+	return def
+}
+
+// Set sets key to value.
+func Set[T any](b *Blackboard, key Key[T], value T) {
+	b.set(key.name, value)
+}
+
+// Delete removes the entry called name, see Scope for which blackboard
+// it is removed from.
+func (b *Blackboard) Delete(name string) {
+	top := b.top()
+	top.commit.RLock()
+	defer top.commit.RUnlock()
+	b.delete(name)
+}
+
+func (b *Blackboard) delete(name string) {
+	if mapped, ok := b.remap[name]; ok {
+		b.parent.delete(mapped)
+		return
+	}
+	b.track(name)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.values, name)
+	if b.tx != nil {
+		b.tx.deleted[name] = true
+	}
+	b.versions[name] = stamp.Add(1)
+}
+
+func (b *Blackboard) get(name string) (any, bool) {
+	if mapped, ok := b.remap[name]; ok {
+		return b.parent.get(mapped)
+	}
+
+	b.mu.RLock()
+	value, ok := b.values[name]
+	deleted := b.tx != nil && b.tx.deleted[name]
+	b.mu.RUnlock()
+	if ok || deleted || b.parent == nil {
+		return value, ok
+	}
+	b.track(name)
+	return b.parent.get(name)
+}
+
+func (b *Blackboard) set(name string, value any) {
+	top := b.top()
+	top.commit.RLock()
+	defer top.commit.RUnlock()
+	b.write(name, value)
+}
+
+// write is set, for callers holding the commit lock.
+func (b *Blackboard) write(name string, value any) {
+	if mapped, ok := b.remap[name]; ok {
+		b.parent.write(mapped, value)
+		return
+	}
+	b.track(name)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.values[name] = value
+	if b.tx != nil {
+		delete(b.tx.deleted, name)
+	}
+	b.versions[name] = stamp.Add(1)
+}
+
+// stamp numbers the changes to all blackboards, so a key's version
+// changes whenever the entry it resolves to does.
+var stamp atomic.Uint64
+
+// version returns the stamp of the last change to the entry name resolves
+// to, or 0 if it was never set.
+func (b *Blackboard) version(name string) uint64 {
+	if mapped, ok := b.remap[name]; ok {
+		return b.parent.version(mapped)
+	}
+
+	b.mu.RLock()
+	version := b.versions[name]
+	_, held := b.values[name]
+	deleted := b.tx != nil && b.tx.deleted[name]
+	b.mu.RUnlock()
+	if held || deleted || b.parent == nil {
+		return version
+	}
+	// The entry may have been deleted here, then changed in the parent.
+	return max(version, b.parent.version(name))
+}
+
+// Entry is a blackboard entry, as listed by Entries.
+type Entry struct {
+	Key   string
+	Value any
+	// Source is the key of the entry in the blackboard holding it, which
+	// differs from Key for remapped entries.
+	Source string
+	// Depth is how many scopes up the entry is held, 0 for entries held
+	// by the blackboard itself.
+	Depth int
+}
+
+// Entries lists the entries visible from b, sorted by key.
+func (b *Blackboard) Entries() []Entry {
+	entries := map[string]Entry{}
+	b.collect(entries, 0)
+	return slices.SortedFunc(maps.Values(entries), func(a, b Entry) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+}
+
+func (b *Blackboard) collect(entries map[string]Entry, depth int) {
+	b.mu.RLock()
+	for name, value := range b.values {
+		entries[name] = Entry{Key: name, Value: value, Source: name, Depth: depth}
+	}
+	b.mu.RUnlock()
+	if b.parent == nil {
+		return
+	}
+
+	inherited := map[string]Entry{}
+	b.parent.collect(inherited, depth+1)
+	for name, mapped := range b.remap {
+		delete(entries, name)
+		if entry, ok := inherited[mapped]; ok {
+			entries[name] = Entry{Key: name, Value: entry.Value, Source: entry.Source, Depth: entry.Depth}
+		}
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for name, entry := range inherited {
+		if _, ok := entries[name]; !ok {
+			_, remapped := b.remap[name]
+			deleted := b.tx != nil && b.tx.deleted[name]
+			if !remapped && !deleted {
+				entries[name] = entry
+			}
+		}
+	}
+}
+
+// String lists the entries visible from b, for debugging.
+func (b *Blackboard) String() string {
+	var sb strings.Builder
+	for _, entry := range b.Entries() {
+		fmt.Fprintf(&sb, "%s = %v", entry.Key, entry.Value)
+		if entry.Depth > 0 {
+			fmt.Fprintf(&sb, " (%s, %d up)", entry.Source, entry.Depth)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Binding is the entry of a key, with the methods of a state.State so it
+// can be used wherever a StateProvider is expected, such as the remapped
+// inputs and outputs of a subtree.
+type Binding[T any] struct {
+	blackboard *Blackboard
+	key        Key[T]
+}
+
+// Bind returns the binding of key in b.
+func Bind[T any](b *Blackboard, key Key[T]) *Binding[T] {
+	return &Binding[T]{blackboard: b, key: key}
+}
+
+// Get returns the value of the entry, or the zero value if it is not set.
+func (e *Binding[T]) Get() T {
+	value, _ := Get(e.blackboard, e.key)
+	return value
+}
+
+func (e *Binding[T]) Set(val T) {
+	Set(e.blackboard, e.key, val)
+}
+
+// Reset deletes the entry.
+func (e *Binding[T]) Reset() {
+	e.blackboard.Delete(e.key.name)
+}
+
+type blackboardKey struct{}
+
+// WithContext returns a context carrying b to the nodes updated with it.
+// Trees do this for the blackboard passed to greenstalk.WithBlackboard.
+func WithContext(ctx context.Context, b *Blackboard) context.Context {
+	return context.WithValue(ctx, blackboardKey{}, b)
+}
+
+// FromContext returns the blackboard carried by ctx, if any.
+func FromContext(ctx context.Context) (*Blackboard, bool) {
+	b, ok := ctx.Value(blackboardKey{}).(*Blackboard)
+	return b, ok
+}
@@ -0,0 +1,200 @@
+package blackboard
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jbcpollak/greenstalk/v2/common/state"
+)
+
+var (
+	target = NewKey[string]("target")
+	ammo   = NewKey[int]("ammo")
+)
+
+func TestGetSet(t *testing.T) {
+	b := New()
+	if _, ok := Get(b, target); ok {
+		t.Errorf("Expected an unset key to be missing")
+	}
+
+	Set(b, target, "door")
+	if value, ok := Get(b, target); !ok || value != "door" {
+		t.Errorf("Expected door, got %q, %v", value, ok)
+	}
+
+	// A key of another type doesn't see the entry.
+	if _, ok := Get(b, NewKey[int]("target")); ok {
+		t.Errorf("Expected a mistyped key to be missing")
+	}
+	if value := GetOr(b, ammo, 3); value != 3 {
+		t.Errorf("Expected the default, got %d", value)
+	}
+
+	b.Delete("target")
+	if _, ok := Get(b, target); ok {
+		t.Errorf("Expected the deleted key to be missing")
+	}
+}
+
+func TestScope(t *testing.T) {
+	parent := New()
+	Set(parent, target, "door")
+	Set(parent, ammo, 10)
+	Set(parent, NewKey[string]("enemy"), "orc")
+
+	child := parent.Scope(map[string]string{"target": "enemy"})
+
+	// Remapped keys read and write the parent's entry.
+	if value, _ := Get(child, target); value != "orc" {
+		t.Errorf("Expected the remapped entry, got %q", value)
+	}
+	Set(child, target, "troll")
+	if value, _ := Get(parent, NewKey[string]("enemy")); value != "troll" {
+		t.Errorf("Expected the write to reach the parent, got %q", value)
+	}
+
+	// Other keys fall back to the parent, but are written locally.
+	if value, _ := Get(child, ammo); value != 10 {
+		t.Errorf("Expected the parent's entry, got %d", value)
+	}
+	Set(child, ammo, 9)
+	if value, _ := Get(parent, ammo); value != 10 {
+		t.Errorf("Expected the parent's entry to be untouched, got %d", value)
+	}
+	if value, _ := Get(child, ammo); value != 9 {
+		t.Errorf("Expected the child's entry, got %d", value)
+	}
+
+	entries := child.Entries()
+	want := []Entry{
+		{Key: "ammo", Value: 9, Source: "ammo", Depth: 0},
+		{Key: "enemy", Value: "troll", Source: "enemy", Depth: 1},
+		{Key: "target", Value: "troll", Source: "enemy", Depth: 1},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, entries)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want[i], entries[i])
+		}
+	}
+	if s := child.String(); s != "ammo = 9\nenemy = troll (enemy, 1 up)\ntarget = troll (enemy, 1 up)\n" {
+		t.Errorf("Unexpected dump %q", s)
+	}
+}
+
+func TestBind(t *testing.T) {
+	b := New()
+	var s state.State[int] = Bind(b, ammo)
+
+	s.Set(4)
+	if value, _ := Get(b, ammo); value != 4 {
+		t.Errorf("Expected the binding to write the entry, got %d", value)
+	}
+	s.Reset()
+	if _, ok := Get(b, ammo); ok || s.Get() != 0 {
+		t.Errorf("Expected Reset to delete the entry")
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	b := New()
+	Set(b, ammo, 10)
+	Set(b, target, "door")
+
+	tx := b.Begin()
+	Set(tx, ammo, 9)
+	tx.Delete("target")
+	if value, _ := Get(b, ammo); value != 10 {
+		t.Errorf("Expected the write to be buffered, got %d", value)
+	}
+	if _, ok := Get(tx, target); ok {
+		t.Errorf("Expected the transaction to see its deletion")
+	}
+	if entries := tx.Entries(); len(entries) != 1 || entries[0].Key != "ammo" {
+		t.Errorf("Expected only ammo to be listed, got %v", entries)
+	}
+
+	tx.Rollback()
+	if value, _ := Get(tx, ammo); value != 10 {
+		t.Errorf("Expected the rollback to discard the write, got %d", value)
+	}
+
+	Set(tx, ammo, 8)
+	tx.Delete("target")
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if value, _ := Get(b, ammo); value != 8 {
+		t.Errorf("Expected the write to be committed, got %d", value)
+	}
+	if _, ok := Get(b, target); ok {
+		t.Errorf("Expected the deletion to be committed")
+	}
+}
+
+func TestTransactionConflict(t *testing.T) {
+	b := New()
+	Set(b, ammo, 10)
+
+	// Two branches take a shot each, from the same view of the ammo.
+	left, right := b.Begin(), b.Begin()
+	Set(left, ammo, GetOr(left, ammo, 0)-1)
+	Set(right, ammo, GetOr(right, ammo, 0)-1)
+	Set(right, target, "troll")
+
+	if err := left.Commit(); err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	err := right.Commit()
+	conflict, ok := err.(*ConflictError)
+	if !ok || len(conflict.Keys) != 1 || conflict.Keys[0] != "ammo" {
+		t.Fatalf("Expected a conflict on ammo, got %v", err)
+	}
+	if value, _ := Get(b, ammo); value != 9 {
+		t.Errorf("Expected the first commit to stand, got %d", value)
+	}
+	if _, ok := Get(b, target); ok {
+		t.Errorf("Expected nothing of the conflicting commit to be applied")
+	}
+
+	// Retried from the new value, the branch commits.
+	Set(right, ammo, GetOr(right, ammo, 0)-1)
+	if err := right.Commit(); err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+	if value, _ := Get(b, ammo); value != 8 {
+		t.Errorf("Expected both shots to count, got %d", value)
+	}
+}
+
+func TestCommitConcurrentWrite(t *testing.T) {
+	for i := range 200 {
+		b := New()
+		tx := b.Scope(map[string]string{"ammo": "ammo"}).Begin()
+		Set(tx, ammo, -1)
+		// Other writes make the commit take long enough for the race to
+		// show.
+		for j := range 100 {
+			Set(tx, NewKey[int](fmt.Sprint(j)), j)
+		}
+
+		// The write either happens before the commit checks for
+		// conflicts, which fails it, or after the commit, which it
+		// overwrites. Either way it must not be lost.
+		start, written := make(chan struct{}), make(chan struct{})
+		go func() {
+			<-start
+			Set(b, ammo, i)
+			close(written)
+		}()
+		close(start)
+		err := tx.Commit()
+		<-written
+		if value, _ := Get(b, ammo); value != i {
+			t.Fatalf("Expected the concurrent write to stand, got %d after commit returned %v", value, err)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+// Package blackboard provides Blackboard, a set of typed values shared by
+// the nodes of a tree, with a scope per subtree.
+//
+// A tree passed greenstalk.WithBlackboard carries its blackboard in the
+// context of every update, and nodes look it up with FromContext:
+//
+//	var target = blackboard.NewKey[string]("target")
+//
+//	func (a *chase) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
+//		b, _ := blackboard.FromContext(ctx)
+//		name, ok := blackboard.Get(b, target)
+//		...
+//	}
+package blackboard
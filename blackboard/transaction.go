@@ -0,0 +1,124 @@
+package blackboard
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// txState is the state of a transaction: the versions of the parent's
+// entries when the transaction first used them, and the keys it deleted.
+// The values it set are the transaction's own values.
+type txState struct {
+	base    map[string]uint64
+	deleted map[string]bool
+}
+
+// ConflictError is returned by Commit when entries written by the
+// transaction were changed in the parent since the transaction first used
+// them.
+type ConflictError struct {
+	Keys []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("conflicting changes to %s", strings.Join(e.Keys, ", "))
+}
+
+// Begin returns a transaction on b: a view of b that keeps its writes and
+// deletions to itself until they are committed with Commit, or discarded
+// with Rollback. Several transactions on b see b, but not each other's
+// pending writes.
+func (b *Blackboard) Begin() *Blackboard {
+	return &Blackboard{
+		values:   map[string]any{},
+		versions: map[string]uint64{},
+		parent:   b,
+		tx: &txState{
+			base:    map[string]uint64{},
+			deleted: map[string]bool{},
+		},
+	}
+}
+
+// IsTransaction reports whether b was returned by Begin.
+func (b *Blackboard) IsTransaction() bool {
+	return b.tx != nil
+}
+
+// track records the version of name in the parent of a transaction, the
+// first time the transaction uses it.
+func (b *Blackboard) track(name string) {
+	if b.tx == nil {
+		return
+	}
+	b.mu.RLock()
+	_, tracked := b.tx.base[name]
+	b.mu.RUnlock()
+	if tracked {
+		return
+	}
+
+	version := b.parent.version(name)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, tracked := b.tx.base[name]; !tracked {
+		b.tx.base[name] = version
+	}
+}
+
+// Commit applies the writes and deletions of transaction b to its parent,
+// unless the parent's entries for any of them changed since b first used
+// them, in which case nothing is applied and Commit returns a
+// *ConflictError. Either way b is emptied, and can be used again. Writes
+// to any blackboard related to b wait for Commit to complete.
+func (b *Blackboard) Commit() error {
+	if b.tx == nil {
+		return errors.New("blackboard is not a transaction")
+	}
+	defer b.Rollback()
+
+	top := b.top()
+	top.commit.Lock()
+	defer top.commit.Unlock()
+
+	b.mu.RLock()
+	values := maps.Clone(b.values)
+	deleted := slices.Collect(maps.Keys(b.tx.deleted))
+	base := maps.Clone(b.tx.base)
+	b.mu.RUnlock()
+
+	var conflicts []string
+	for _, name := range slices.Concat(slices.Collect(maps.Keys(values)), deleted) {
+		if b.parent.version(name) != base[name] {
+			conflicts = append(conflicts, name)
+		}
+	}
+	if len(conflicts) > 0 {
+		slices.Sort(conflicts)
+		return &ConflictError{Keys: conflicts}
+	}
+
+	for name, value := range values {
+		b.parent.write(name, value)
+	}
+	for _, name := range deleted {
+		b.parent.delete(name)
+	}
+	return nil
+}
+
+// Rollback discards the writes and deletions of transaction b.
+func (b *Blackboard) Rollback() {
+	if b.tx == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	clear(b.values)
+	clear(b.versions)
+	clear(b.tx.base)
+	clear(b.tx.deleted)
+}
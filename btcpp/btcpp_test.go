@@ -0,0 +1,333 @@
+package btcpp_test
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jbcpollak/greenstalk/v2"
+	"github.com/jbcpollak/greenstalk/v2/btcpp"
+	"github.com/jbcpollak/greenstalk/v2/common/action"
+	"github.com/jbcpollak/greenstalk/v2/common/composite"
+	"github.com/jbcpollak/greenstalk/v2/common/condition"
+	"github.com/jbcpollak/greenstalk/v2/common/decorator"
+	"github.com/jbcpollak/greenstalk/v2/common/state"
+	"github.com/jbcpollak/greenstalk/v2/common/subtree"
+	"github.com/jbcpollak/greenstalk/v2/core"
+	"github.com/jbcpollak/greenstalk/v2/registry"
+)
+
+const groot = `<?xml version="1.0" encoding="UTF-8"?>
+<root BTCPP_format="4" main_tree_to_execute="Main">
+  <BehaviorTree ID="Main">
+    <Sequence name="Root">
+      <Fallback name="Open">
+        <AlwaysFailure/>
+        <RetryUntilSuccessful num_attempts="3">
+          <Say name="Knock" message="knock" said="said"/>
+        </RetryUntilSuccessful>
+      </Fallback>
+      <Delay delay_msec="1">
+        <SubTree ID="Greet" _autoremap="true"/>
+      </Delay>
+    </Sequence>
+  </BehaviorTree>
+  <BehaviorTree ID="Greet">
+    <Repeat name="Twice" num_cycles="2">
+      <Parallel success_count="-1">
+        <Say name="Hello" message="hello" said="said"/>
+        <AlwaysSuccess/>
+      </Parallel>
+    </Repeat>
+  </BehaviorTree>
+  <TreeNodesModel>
+    <Action ID="Say"/>
+  </TreeNodesModel>
+</root>`
+
+type sayParams struct {
+	core.BaseParams
+
+	Message string
+	Said    *[]string
+}
+
+type say struct {
+	core.Leaf[sayParams]
+}
+
+func (s *say) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
+	*s.Params.Said = append(*s.Params.Said, s.Params.Message)
+	return core.SuccessResult()
+}
+
+func (s *say) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
+	return core.SuccessResult()
+}
+
+func (s *say) Leave(context.Context) error {
+	return nil
+}
+
+func newSay(params sayParams) *say {
+	return &say{Leaf: core.NewLeaf(params)}
+}
+
+// script is a custom node equivalent to BehaviorTree.CPP's Script.
+type script struct {
+	core.Leaf[core.BaseParams]
+	code string
+}
+
+func (s *script) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
+	return core.SuccessResult()
+}
+
+func (s *script) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
+	return core.SuccessResult()
+}
+
+func (s *script) Leave(context.Context) error {
+	return nil
+}
+
+func (s *script) StandardNode() (string, []core.Port) {
+	return "Script", []core.Port{{Name: "code", Value: s.code}}
+}
+
+func sayRegistry(said *[]string) *registry.Registry {
+	r := registry.New()
+	registry.RegisterLeaf(r, "Say", newSay)
+	r.RegisterValue("said", said)
+	return r
+}
+
+func TestImport(t *testing.T) {
+	var said []string
+	root, err := btcpp.Import([]byte(groot), sayRegistry(&said))
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	tree, err := greenstalk.NewBehaviorTree(root, greenstalk.WithValidation())
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	for _, path := range []string{"Root.Open.Retry.Knock", "Root.AsyncDelayer.Twice.Parallel.Hello"} {
+		if _, ok := tree.NodeByPath(path); !ok {
+			t.Errorf("Expected a node at %s", path)
+		}
+	}
+
+	result, err := tree.RunToCompletion(t.Context(), core.DefaultEvent{})
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if result.Status() != core.StatusSuccess {
+		t.Errorf("Expected success, got %v", result)
+	}
+	if strings.Join(said, " ") != "knock hello hello" {
+		t.Errorf("Unexpected order of execution: %v", said)
+	}
+}
+
+func TestImportVersion3(t *testing.T) {
+	var said []string
+	root, err := btcpp.Import([]byte(`<root>
+  <BehaviorTree ID="Only">
+    <Control ID="Sequence">
+      <Action ID="Say" message="hi" said="said"/>
+      <Decorator ID="Inverter"><Action ID="AlwaysFailure"/></Decorator>
+    </Control>
+  </BehaviorTree>
+</root>`), sayRegistry(&said))
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	tree, err := greenstalk.NewBehaviorTree(root)
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if status := tree.Update(t.Context(), core.DefaultEvent{}).Status(); status != core.StatusSuccess {
+		t.Errorf("Expected success, got %v", status)
+	}
+	if strings.Join(said, " ") != "hi" {
+		t.Errorf("Expected the custom action to run, got %v", said)
+	}
+}
+
+func TestImportParallelCounts(t *testing.T) {
+	def, err := btcpp.Definition([]byte(`<root><BehaviorTree ID="A">
+  <Parallel success_count="-2" failure_count="-1">
+    <AlwaysSuccess/>
+    <AlwaysSuccess/>
+    <AlwaysFailure/>
+  </Parallel>
+</BehaviorTree></root>`))
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if def.Params["successRequired"] != 2 || def.Params["failureRequired"] != 3 {
+		t.Errorf("Expected counts relative to the 3 children, got %v", def.Params)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		document string
+		expected string
+	}{
+		{"cycle", `<root main_tree_to_execute="A">
+			<BehaviorTree ID="A"><SubTree ID="B"/></BehaviorTree>
+			<BehaviorTree ID="B"><Sequence><SubTree ID="A"/></Sequence></BehaviorTree>
+		</root>`, `BehaviorTree "A" includes itself`},
+		{"no main tree", `<root><BehaviorTree ID="A"><AlwaysSuccess/></BehaviorTree><BehaviorTree ID="B"><AlwaysSuccess/></BehaviorTree></root>`, "main_tree_to_execute is required"},
+		{"unknown node", `<root><BehaviorTree ID="A"><Teleport/></BehaviorTree></root>`, `unknown node type "Teleport"`},
+		{"bad port", `<root><BehaviorTree ID="A"><Repeat num_cycles="twice"><AlwaysSuccess/></Repeat></BehaviorTree></root>`, "port num_cycles"},
+		{"parallel count", `<root><BehaviorTree ID="A"><Parallel success_count="-3"><AlwaysSuccess/><AlwaysSuccess/></Parallel></BehaviorTree></root>`, "port success_count: -3 is less than minus the 2 children"},
+		{"subtree remap", `<root main_tree_to_execute="A">
+			<BehaviorTree ID="A"><SubTree ID="B" target="{enemy}"/></BehaviorTree>
+			<BehaviorTree ID="B"><AlwaysSuccess/></BehaviorTree>
+		</root>`, `SubTree "B": remapping port target is not supported`},
+	}
+	for _, c := range cases {
+		_, err := btcpp.Import([]byte(c.document), nil)
+		if err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", c.name, c.expected, err)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	var said []string
+	root := composite.SequenceNamed("Root",
+		composite.SelectorNamed("Open",
+			action.Fail(action.FailParams{}),
+			decorator.Retry(decorator.RetryParams{BaseParams: "Retry", Attempts: 3},
+				newSay(sayParams{BaseParams: "Knock", Message: "knock", Said: &said}),
+			),
+		),
+		decorator.AsyncDelayer(decorator.AsyncDelayerParams{BaseParams: "Wait", Delay: time.Millisecond},
+			composite.Parallel(0, 1,
+				newSay(sayParams{BaseParams: "Hello", Message: "hello", Said: &said}),
+				action.Succeed(action.SucceedParams{}),
+			),
+		),
+		&script{Leaf: core.NewLeaf(core.BaseParams("Reset")), code: "count := 0"},
+	)
+
+	data, err := btcpp.Export(root, "Main")
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	for _, expected := range []string{
+		`<root BTCPP_format="4" main_tree_to_execute="Main">`,
+		`<Fallback name="Open">`,
+		`<RetryUntilSuccessful name="Retry" num_attempts="3">`,
+		`<Say name="Knock" message="knock"></Say>`,
+		`<Delay name="Wait" delay_msec="1">`,
+		`<Parallel name="Parallel" success_count="-1" failure_count="1">`,
+		`<AlwaysSuccess></AlwaysSuccess>`,
+		`<Script name="Reset" code="count := 0"></Script>`,
+		`<Action ID="Say"></Action>`,
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected %s in\n%s", expected, data)
+		}
+	}
+	if strings.Contains(string(data), `ID="Script"`) {
+		t.Errorf("Expected standard nodes not to be listed in the TreeNodesModel:\n%s", data)
+	}
+
+	// The exported document imports back to the same tree, once the
+	// ports that can't be written are filled in by the registry.
+	def, err := btcpp.Definition(data)
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if def.Type != "Sequence" || def.Children[1].Type != "AsyncDelayer" || def.Children[1].Params["delay"] != "1ms" {
+		t.Errorf("Unexpected definition %+v", def)
+	}
+}
+
+// TestExportRoundTrip checks that every node registered by registry.New
+// is exported under a type that imports back to the same node.
+func TestExportRoundTrip(t *testing.T) {
+	leaf := func() core.Node { return action.Succeed(action.SucceedParams{}) }
+	nodes := map[string]core.Node{
+		"Succeed":             action.Succeed(action.SucceedParams{BaseParams: "Done"}),
+		"Fail":                action.Fail(action.FailParams{}),
+		"Counter":             action.Counter(action.CounterParams{BaseParams: "Count", Limit: 3}),
+		"FunctionAction":      action.FunctionAction(action.FunctionActionParams{BaseParams: "Do"}),
+		"AsyncFunctionAction": action.AsyncFunctionAction(action.AsyncFunctionActionParams{BaseParams: "Do"}),
+		"Signaller":           action.Signaller(action.SignallerParams[any]{BaseParams: "Signal"}),
+		"Sequence":            composite.Sequence(leaf(), leaf()),
+		"Selector":            composite.Selector(leaf()),
+		"ActiveSequence":      composite.ActiveSequence(leaf()),
+		"PersistentSequence":  composite.PersistentSequence(leaf()),
+		"RandomSequence":      composite.RandomSequence(leaf()),
+		"RandomSelector":      composite.RandomSelector(leaf()),
+		"Parallel":            composite.Parallel(2, 1, leaf(), leaf(), leaf()),
+		"Switch":              condition.Switch(nil, leaf(), leaf()),
+		"SwitchMap":           condition.SwitchMap(nil, map[string]core.Node{"a": leaf(), "b": leaf()}),
+		"Inverter":            decorator.Inverter(leaf()),
+		"UntilFailure":        decorator.UntilFailure(leaf()),
+		"UntilSuccess":        decorator.UntilSuccess(leaf()),
+		"Transaction":         decorator.Transaction(leaf()),
+		"RepeatUntil":         decorator.RepeatUntil(decorator.RepeatUntilParams{BaseParams: "Until"}, leaf()),
+		"Repeat":              decorator.Repeat(decorator.RepeatParams{BaseParams: "Twice", Cycles: 2}, leaf()),
+		"Retry":               decorator.Retry(decorator.RetryParams{BaseParams: "Knock", Attempts: 3}, leaf()),
+		"Delayer":             decorator.Delayer(decorator.DelayerParams{BaseParams: "Wait", Delay: time.Second}, leaf()),
+		"AsyncDelayer":        decorator.AsyncDelayer(decorator.AsyncDelayerParams{BaseParams: "Wait", Delay: time.Second}, leaf()),
+		"With":                decorator.With(nil, leaf()),
+		"WithAsync":           decorator.WithAsync(nil, nil, leaf()),
+		"DynamicDecorator":    decorator.DynamicDecorator(nil),
+		"SubTree":             subtree.SubTree(subtree.SubTreeParams{Template: "Greet"}),
+		"StateReset":          state.MakeStateResetAction(),
+	}
+	r := registry.New()
+	if types := slices.Sorted(maps.Keys(nodes)); !slices.Equal(types, r.Types()) {
+		t.Fatalf("Expected a node of every registered type %v, got %v", r.Types(), types)
+	}
+
+	for typ, node := range nodes {
+		data, err := btcpp.Export(node, "Main")
+		if err != nil {
+			t.Errorf("%s: unexpectedly got %v", typ, err)
+			continue
+		}
+		def, err := btcpp.Definition(data)
+		if err != nil {
+			t.Errorf("%s: unexpectedly got %v", typ, err)
+			continue
+		}
+		expected := typ
+		if typ == "Delayer" {
+			// Written as BehaviorTree.CPP's Delay, which is asynchronous.
+			expected = "AsyncDelayer"
+		}
+		if def.Type != expected {
+			t.Errorf("%s: exported as %s in\n%s", typ, def.Type, data)
+		}
+
+		imported, err := btcpp.Import(data, r)
+		if err != nil {
+			t.Errorf("%s: unexpectedly got %v", typ, err)
+			continue
+		}
+		again, err := btcpp.Export(imported, "Main")
+		if err != nil {
+			t.Errorf("%s: unexpectedly got %v", typ, err)
+			continue
+		}
+		if !bytes.Equal(again, data) {
+			t.Errorf("%s: exported\n%s\nbut imported\n%s", typ, data, again)
+		}
+	}
+}
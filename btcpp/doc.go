@@ -0,0 +1,31 @@
+// Package btcpp reads and writes trees in the XML format of BehaviorTree.CPP,
+// which is also the format used by the Groot editor.
+//
+// Import maps the standard BehaviorTree.CPP nodes onto common/:
+//
+//	Sequence              composite.Sequence
+//	SequenceWithMemory    composite.PersistentSequence
+//	ReactiveSequence      composite.ActiveSequence
+//	Fallback              composite.Selector
+//	Parallel              composite.Parallel
+//	Inverter              decorator.Inverter
+//	Delay                 decorator.AsyncDelayer
+//	Repeat                decorator.Repeat
+//	RetryUntilSuccessful  decorator.Retry
+//	AlwaysSuccess         action.Succeed
+//	AlwaysFailure         action.Fail
+//	SubTree               the referenced BehaviorTree, inlined
+//
+// Inlined SubTrees share the blackboard of the tree including them, so
+// port remapping attributes on a SubTree are rejected. A SubTree whose ID
+// is not a BehaviorTree of the document is built by the registry's
+// SubTree, with the ID as its template.
+//
+// Any other node is built by the registry passed to Import, using its
+// element name (or its ID attribute in the version 3 format) as the type
+// and its attributes as params, lists being separated by semicolons.
+// Export does the reverse, writing nodes that implement core.Standard as
+// their standard equivalent, nodes that implement core.Custom as they
+// describe themselves and other nodes under the name of their Go type,
+// which for the nodes of common/ is the type they are registered under.
+package btcpp
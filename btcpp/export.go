@@ -0,0 +1,219 @@
+package btcpp
+
+import (
+	"encoding/xml"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+// modelTags are the TreeNodesModel elements for each category of node.
+var modelTags = map[core.Category]string{
+	core.CategoryLeaf:      "Action",
+	core.CategoryDecorator: "Decorator",
+	core.CategoryComposite: "Control",
+}
+
+func attr(name, value string) xml.Attr {
+	return xml.Attr{Name: xml.Name{Local: name}, Value: value}
+}
+
+// Export writes the tree rooted at root as a BehaviorTree.CPP version 4
+// document, with a single BehaviorTree called id. Nodes implementing
+// core.Standard are written as their standard equivalent, and nodes
+// implementing core.Custom as they describe themselves. Other nodes are
+// written under their Go type name, with the plain fields of their Params
+// as ports. Custom nodes are listed in the TreeNodesModel so that Groot
+// can display them.
+func Export(root core.Node, id string) ([]byte, error) {
+	e := exporter{models: map[string]core.Category{}}
+	tree, err := e.node(root)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := document{
+		Format: "4",
+		Main:   id,
+		Trees: []element{{
+			XMLName:  xml.Name{Local: "BehaviorTree"},
+			Attrs:    []xml.Attr{attr("ID", id)},
+			Children: []element{tree},
+		}},
+	}
+	if len(e.models) > 0 {
+		doc.Models = &models{}
+		for _, typ := range slices.Sorted(maps.Keys(e.models)) {
+			doc.Models.Nodes = append(doc.Models.Nodes, element{
+				XMLName: xml.Name{Local: modelTags[e.models[typ]]},
+				Attrs:   []xml.Attr{attr("ID", typ)},
+			})
+		}
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+type exporter struct {
+	// models holds the custom node types written, by category.
+	models map[string]core.Category
+}
+
+func (e exporter) node(node core.Node) (element, error) {
+	v := reflect.ValueOf(node)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return element{}, fmt.Errorf("cannot export node %s of type %T", node.Name(), node)
+	}
+
+	el := element{}
+	if name := baseName(v, node); name != "" {
+		el.Attrs = append(el.Attrs, attr("name", name))
+	}
+
+	var children []core.Node
+	if parent, ok := node.(interface{ ChildNodes() []core.Node }); ok {
+		children = parent.ChildNodes()
+	}
+
+	switch n := node.(type) {
+	case core.Standard:
+		typ, ports := n.StandardNode()
+		el.XMLName.Local = typ
+		el.Attrs = append(el.Attrs, portAttrs(ports)...)
+	case core.Custom:
+		typ, ports, custom := n.CustomNode()
+		el.XMLName.Local = typ
+		el.Attrs = append(el.Attrs, portAttrs(ports)...)
+		children = custom
+		if typ != "SubTree" {
+			e.models[typ] = node.Category()
+		}
+	default:
+		typ := typeName(v.Type())
+		el.XMLName.Local = typ
+		el.Attrs = append(el.Attrs, paramPorts(v)...)
+		e.models[typ] = node.Category()
+	}
+
+	elements, err := e.children(children)
+	if err != nil {
+		return element{}, err
+	}
+	el.Children = elements
+	return el, nil
+}
+
+func portAttrs(ports []core.Port) []xml.Attr {
+	var attrs []xml.Attr
+	for _, port := range ports {
+		attrs = append(attrs, attr(port.Name, port.Value))
+	}
+	return attrs
+}
+
+func (e exporter) children(nodes []core.Node) ([]element, error) {
+	var children []element
+	for _, child := range nodes {
+		if child == nil {
+			continue
+		}
+		el, err := e.node(child)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, el)
+	}
+	return children, nil
+}
+
+// baseName returns the name a node was given, without the prefix its
+// Params may add, such as action.Succeed's.
+func baseName(v reflect.Value, node core.Node) string {
+	params := v.FieldByName("Params")
+	if params.IsValid() {
+		if params.Type() == reflect.TypeFor[core.BaseParams]() {
+			return params.String()
+		}
+		if params.Kind() == reflect.Struct {
+			if base := params.FieldByName("BaseParams"); base.IsValid() {
+				return base.String()
+			}
+		}
+	}
+	return node.Name()
+}
+
+// paramPorts writes the plain fields of a node's Params as ports.
+func paramPorts(v reflect.Value) []xml.Attr {
+	params := v.FieldByName("Params")
+	if !params.IsValid() || params.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var attrs []xml.Attr
+	for i := 0; i < params.NumField(); i++ {
+		field := params.Type().Field(i)
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		if value, ok := port(params.Field(i)); ok {
+			attrs = append(attrs, attr(lowerFirst(field.Name), value))
+		}
+	}
+	return attrs
+}
+
+func port(v reflect.Value) (string, bool) {
+	if v.Type() == reflect.TypeFor[time.Duration]() {
+		return time.Duration(v.Int()).String(), true
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), true
+	}
+	return "", false
+}
+
+// typeName turns a Go type name such as function_action or randomSequence
+// into a node type name such as FunctionAction or RandomSequence.
+func typeName(t reflect.Type) string {
+	name, _, _ := strings.Cut(t.Name(), "[")
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	return b.String()
+}
+
+func lowerFirst(s string) string {
+	runes := []rune(s)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
@@ -0,0 +1,263 @@
+package btcpp
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jbcpollak/greenstalk/v2/core"
+	"github.com/jbcpollak/greenstalk/v2/registry"
+)
+
+// element is any XML element of a BehaviorTree.CPP document.
+type element struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []element  `xml:",any"`
+}
+
+func (e element) attr(name string) (string, bool) {
+	for _, attr := range e.Attrs {
+		if attr.Name.Local == name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// document is the root element of a BehaviorTree.CPP document.
+type document struct {
+	XMLName xml.Name  `xml:"root"`
+	Format  string    `xml:"BTCPP_format,attr,omitempty"`
+	Main    string    `xml:"main_tree_to_execute,attr,omitempty"`
+	Trees   []element `xml:"BehaviorTree"`
+	Models  *models   `xml:"TreeNodesModel,omitempty"`
+}
+
+// models lists the custom nodes used by a document, for Groot.
+type models struct {
+	Nodes []element `xml:",any"`
+}
+
+// genericTags are the version 3 elements that name their node type in an
+// ID attribute.
+var genericTags = map[string]bool{
+	"Action":    true,
+	"Condition": true,
+	"Decorator": true,
+	"Control":   true,
+}
+
+// Import builds the main tree of a BehaviorTree.CPP XML document: the one
+// named by main_tree_to_execute, or the only BehaviorTree in it. Nodes
+// without a standard mapping are built by r, which may be nil to use
+// registry.New.
+func Import(data []byte, r *registry.Registry) (core.Node, error) {
+	if r == nil {
+		r = registry.New()
+	}
+
+	def, err := Definition(data)
+	if err != nil {
+		return nil, err
+	}
+	return r.Build(def)
+}
+
+// Definition translates the main tree of a BehaviorTree.CPP XML document
+// into a registry.Definition, see Import.
+func Definition(data []byte) (registry.Definition, error) {
+	var doc document
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return registry.Definition{}, fmt.Errorf("decoding BehaviorTree.CPP document: %w", err)
+	}
+
+	trees := make(map[string]element, len(doc.Trees))
+	for _, tree := range doc.Trees {
+		id, _ := tree.attr("ID")
+		if _, ok := trees[id]; ok {
+			return registry.Definition{}, fmt.Errorf("duplicate BehaviorTree %q", id)
+		}
+		trees[id] = tree
+	}
+
+	main := doc.Main
+	if main == "" {
+		if len(doc.Trees) != 1 {
+			return registry.Definition{}, errors.New("main_tree_to_execute is required when there is more than one BehaviorTree")
+		}
+		main, _ = doc.Trees[0].attr("ID")
+	}
+
+	c := converter{trees: trees, expanding: map[string]bool{}}
+	return c.tree(main)
+}
+
+type converter struct {
+	trees map[string]element
+	// expanding holds the BehaviorTrees being inlined, to catch cycles.
+	expanding map[string]bool
+}
+
+func (c converter) tree(id string) (registry.Definition, error) {
+	tree, ok := c.trees[id]
+	if !ok {
+		return registry.Definition{}, fmt.Errorf("unknown BehaviorTree %q", id)
+	}
+	if c.expanding[id] {
+		return registry.Definition{}, fmt.Errorf("BehaviorTree %q includes itself", id)
+	}
+	if len(tree.Children) != 1 {
+		return registry.Definition{}, fmt.Errorf("BehaviorTree %q must have exactly one root node, got %d", id, len(tree.Children))
+	}
+
+	c.expanding[id] = true
+	defer delete(c.expanding, id)
+
+	def, err := c.node(tree.Children[0])
+	if err != nil {
+		return registry.Definition{}, fmt.Errorf("BehaviorTree %q: %w", id, err)
+	}
+	return def, nil
+}
+
+func (c converter) node(e element) (registry.Definition, error) {
+	typ := e.XMLName.Local
+	if genericTags[typ] {
+		id, ok := e.attr("ID")
+		if !ok {
+			return registry.Definition{}, fmt.Errorf("%s without an ID", typ)
+		}
+		typ = id
+	}
+
+	if typ == "SubTree" {
+		id, _ := e.attr("ID")
+		// SubTrees are inlined, sharing the blackboard of the tree
+		// including them, so their ports can't be remapped.
+		for _, attr := range e.Attrs {
+			if name := attr.Name.Local; name != "ID" && name != "name" && !strings.HasPrefix(name, "_") {
+				return registry.Definition{}, fmt.Errorf("SubTree %q: remapping port %s is not supported", id, name)
+			}
+		}
+		if _, ok := c.trees[id]; !ok {
+			// Not in the document: a template of a subtree.Library,
+			// built by the registry's SubTree.
+			name, _ := e.attr("name")
+			return registry.Definition{Type: "SubTree", Name: name, Params: map[string]any{"template": id}}, nil
+		}
+		return c.tree(id)
+	}
+
+	def := registry.Definition{Type: typ}
+	params := map[string]any{}
+	for _, attr := range e.Attrs {
+		switch name := attr.Name.Local; {
+		case name == "name":
+			def.Name = attr.Value
+		case name == "ID" && genericTags[e.XMLName.Local]:
+		case strings.HasPrefix(name, "_"):
+			// Editor metadata such as _description.
+		default:
+			params[name] = attr.Value
+		}
+	}
+
+	if err := standardNode(&def, params, len(e.Children)); err != nil {
+		return registry.Definition{}, fmt.Errorf("%s: %w", typ, err)
+	}
+	if len(params) > 0 {
+		def.Params = params
+	}
+
+	for _, child := range e.Children {
+		childDef, err := c.node(child)
+		if err != nil {
+			return registry.Definition{}, err
+		}
+		def.Children = append(def.Children, childDef)
+	}
+	return def, nil
+}
+
+// standardNode maps a standard BehaviorTree.CPP node and its ports onto
+// the registry type and params of its common/ equivalent, which has the
+// given number of children.
+func standardNode(def *registry.Definition, params map[string]any, children int) error {
+	switch def.Type {
+	case "Sequence", "Parallel", "Inverter", "Repeat":
+	case "SequenceWithMemory", "SequenceStar":
+		def.Type = "PersistentSequence"
+	case "ReactiveSequence":
+		def.Type = "ActiveSequence"
+	case "Fallback":
+		def.Type = "Selector"
+	case "Delay":
+		def.Type = "AsyncDelayer"
+	case "RetryUntilSuccessful":
+		def.Type = "Retry"
+	case "AlwaysSuccess":
+		def.Type = "Succeed"
+	case "AlwaysFailure":
+		def.Type = "Fail"
+	default:
+		return nil
+	}
+
+	var errs []error
+	port := func(names []string, param string, convert func(string) (any, error)) {
+		for _, name := range names {
+			value, ok := params[name]
+			if !ok {
+				continue
+			}
+			delete(params, name)
+			converted, err := convert(value.(string))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("port %s: %w", name, err))
+				continue
+			}
+			params[param] = converted
+		}
+	}
+
+	switch def.Type {
+	case "Parallel":
+		// Negative counts are relative to the number of children in
+		// BehaviorTree.CPP, -1 meaning every child.
+		threshold := func(s string) (any, error) {
+			n, err := strconv.Atoi(s)
+			if err != nil || n >= 0 {
+				return n, err
+			}
+			if n < -children {
+				return nil, fmt.Errorf("%d is less than minus the %d children", n, children)
+			}
+			return children + n + 1, nil
+		}
+		if _, ok := params["failure_count"]; !ok {
+			if _, ok := params["failure_threshold"]; !ok {
+				// BehaviorTree.CPP fails on the first failure by default.
+				params["failure_count"] = "1"
+			}
+		}
+		port([]string{"success_count", "success_threshold"}, "successRequired", threshold)
+		port([]string{"failure_count", "failure_threshold"}, "failureRequired", threshold)
+	case "AsyncDelayer":
+		port([]string{"delay_msec"}, "delay", func(s string) (any, error) {
+			ms, err := strconv.Atoi(s)
+			return strconv.Itoa(ms) + "ms", err
+		})
+	case "Repeat":
+		port([]string{"num_cycles"}, "cycles", integer)
+	case "Retry":
+		port([]string{"num_attempts"}, "attempts", integer)
+	}
+	return errors.Join(errs...)
+}
+
+func integer(s string) (any, error) {
+	return strconv.Atoi(s)
+}
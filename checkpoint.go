@@ -0,0 +1,108 @@
+package greenstalk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+// Checkpoint is the runtime state of every node in a tree, keyed by path,
+// the indices of the children leading from the root to the node, such as
+// "0/1/0" for the first child of the root's second child. It can be
+// encoded as JSON and restored into a freshly built tree of the same
+// shape, see Tree.Checkpoint.
+type Checkpoint struct {
+	Nodes map[string]core.NodeState `json:"nodes"`
+}
+
+// Checkpoint saves the runtime state of every node: its status, the
+// current child of composites and the state of core.Stateful nodes. It is
+// safe to call while EventLoop is running.
+func (bt *Tree) Checkpoint(ctx context.Context) (Checkpoint, error) {
+	cp := Checkpoint{Nodes: map[string]core.NodeState{}}
+	var errs []error
+	err := bt.Do(ctx, func() {
+		walkPaths(bt.root, "0", func(node core.Node, path string) {
+			state, err := core.CheckpointNode(node)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", node.FullName(), err))
+			}
+			cp.Nodes[path] = state
+		})
+	})
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	if err := errors.Join(errs...); err != nil {
+		return Checkpoint{}, err
+	}
+	return cp, nil
+}
+
+// Restore loads a checkpoint into the tree, which must have been freshly
+// built with the same shape as the tree it was taken from and not have
+// run yet. Running leaves are activated again when the tree next runs,
+// restarting their work, while composites and decorators resume where
+// they left off. If Restore fails, the tree is left partially restored
+// and should be rebuilt.
+func (bt *Tree) Restore(ctx context.Context, cp Checkpoint) error {
+	var errs []error
+	err := bt.Do(ctx, func() {
+		if bt.root.Result().Status() != core.StatusInvalid {
+			errs = append(errs, errors.New("checkpoints can only be restored into a tree that has not run"))
+			return
+		}
+
+		restored := map[string]bool{}
+		// Nodes are restored parents first, so a DynamicDecorator can
+		// recreate its child before the child is visited.
+		walkPaths(bt.root, "0", func(node core.Node, path string) {
+			state, ok := cp.Nodes[path]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s (%s) is not in the checkpoint", node.FullName(), path))
+				return
+			}
+			restored[path] = true
+			if state.Name != node.FullName() {
+				errs = append(errs, fmt.Errorf("%s (%s) is %s in the checkpoint", node.FullName(), path, state.Name))
+				return
+			}
+			if err := core.RestoreNode(node, state); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", node.FullName(), err))
+			}
+		})
+		for _, path := range slices.Sorted(maps.Keys(cp.Nodes)) {
+			if !restored[path] {
+				errs = append(errs, fmt.Errorf("%s (%s) is not in the tree", cp.Nodes[path].Name, path))
+			}
+		}
+
+		bt.index.build(bt.root)
+		bt.publish()
+	})
+	if err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// walkPaths calls fn for node and its descendants, parents first, along
+// with their path in a Checkpoint. Children are listed after fn returns,
+// so fn may replace the children of the node it is called with.
+func walkPaths(node core.Node, path string, fn func(node core.Node, path string)) {
+	fn(node, path)
+	parent, ok := node.(interface{ ChildNodes() []core.Node })
+	if !ok {
+		return
+	}
+	for i, child := range parent.ChildNodes() {
+		if child != nil {
+			walkPaths(child, path+"/"+strconv.Itoa(i), fn)
+		}
+	}
+}
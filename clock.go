@@ -0,0 +1,51 @@
+package greenstalk
+
+import (
+	"time"
+
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+// tickClock drives a clock-driven tree. With a fixed interval, min and max
+// are equal. Otherwise the interval starts at min and doubles, up to max,
+// every tick that neither follows a queued event nor changes the status of
+// the root, so an idle tree backs off while a busy one ticks at full rate.
+type tickClock struct {
+	min, max time.Duration
+	interval time.Duration
+	tick     uint64
+	last     time.Time
+}
+
+func newTickClock(min, max time.Duration) *tickClock {
+	return &tickClock{min: min, max: max, interval: min}
+}
+
+func (c *tickClock) start(now time.Time) {
+	c.tick = 0
+	c.last = now
+	c.interval = c.min
+}
+
+// next returns the event for a tick firing at now.
+func (c *tickClock) next(now time.Time) core.TickEvent {
+	c.tick++
+	evt := core.TickEvent{
+		Tick:  c.tick,
+		Time:  now,
+		Delta: now.Sub(c.last),
+	}
+	c.last = now
+	return evt
+}
+
+// adapt adjusts the interval to wait before the next tick, depending on
+// whether the tree did anything since the last one.
+func (c *tickClock) adapt(active bool) time.Duration {
+	if active {
+		c.interval = c.min
+	} else {
+		c.interval = min(c.interval*2, c.max)
+	}
+	return c.interval
+}
@@ -2,6 +2,7 @@ package action
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -13,14 +14,25 @@ type AsyncFunctionActionParams struct {
 	Func func(ctx context.Context) core.ResultDetails
 }
 
+func (p AsyncFunctionActionParams) Validate(context.Context) error {
+	if p.Func == nil {
+		return errors.New("Func is nil")
+	}
+	return nil
+}
+
 type asyncFunctionFinishedEvent struct {
 	targetNodeId uuid.UUID
+	generation   uint64
+	result       core.ResultDetails
 }
 
 func (e asyncFunctionFinishedEvent) TargetNodeId() uuid.UUID {
 	return e.targetNodeId
 }
 
+func (e asyncFunctionFinishedEvent) ControlEvent() {}
+
 // Same as FunctionAction but the function is executed in a separate goroutine. Returns the same status as the function,
 // except StatusRunning which return an ErrorResult.
 func AsyncFunctionAction(params AsyncFunctionActionParams) *asyncFunctionAction {
@@ -30,29 +42,33 @@ func AsyncFunctionAction(params AsyncFunctionActionParams) *asyncFunctionAction
 
 type asyncFunctionAction struct {
 	core.Leaf[AsyncFunctionActionParams]
-
-	fnResult core.ResultDetails
 }
 
 func (a *asyncFunctionAction) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
-	return core.InitRunningResult(a.performFunction)
+	generation := a.Generation()
+	return core.InitRunningResult(func(ctx context.Context, enqueue core.EnqueueFn) error {
+		return a.performFunction(ctx, enqueue, generation)
+	})
 }
 
-func (a *asyncFunctionAction) performFunction(ctx context.Context, enqueue core.EnqueueFn) error {
-	a.fnResult = a.Params.Func(ctx)
-	if a.fnResult.Status() == core.StatusRunning {
-		a.fnResult = core.ErrorResult(fmt.Errorf("async function returned invalid status of StatusRunning"))
+func (a *asyncFunctionAction) performFunction(ctx context.Context, enqueue core.EnqueueFn, generation uint64) error {
+	result := a.Params.Func(ctx)
+	if result.Status() == core.StatusRunning {
+		result = core.ErrorResult(fmt.Errorf("async function returned invalid status of StatusRunning"))
 	}
 
 	return enqueue(asyncFunctionFinishedEvent{
 		targetNodeId: a.Id(),
+		generation:   generation,
+		result:       result,
 	})
 }
 
 func (a *asyncFunctionAction) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
 	if afe, ok := evt.(asyncFunctionFinishedEvent); ok {
-		if afe.TargetNodeId() == a.Id() {
-			return a.fnResult
+		// Ignore completions left over from an earlier activation.
+		if afe.TargetNodeId() == a.Id() && afe.generation == a.Generation() {
+			return afe.result
 		}
 	}
 
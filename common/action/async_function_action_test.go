@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/jbcpollak/greenstalk/v2"
 	"github.com/jbcpollak/greenstalk/v2/core"
@@ -93,3 +94,99 @@ func testAsyncFunctionAction(t *testing.T, expectedStatus core.Status, fn func(c
 		t.Errorf("Expected %v got %v", expectedStatus, asyncFunctionAction.Result().Status())
 	}
 }
+
+func TestAsyncFunctionActionCancelledOnHalt(t *testing.T) {
+	started := make(chan struct{})
+	cancelled := make(chan error)
+
+	asyncFunctionAction := AsyncFunctionAction(AsyncFunctionActionParams{
+		BaseParams: "blocking",
+		Func: func(ctx context.Context) core.ResultDetails {
+			close(started)
+			<-ctx.Done()
+			cancelled <- ctx.Err()
+			return core.FailureResult()
+		},
+	})
+
+	tree, err := greenstalk.NewBehaviorTree(asyncFunctionAction)
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	// The tree context stays alive, only the node is halted.
+	ctx := t.Context()
+	if status := tree.Update(ctx, core.DefaultEvent{}).Status(); status != core.StatusRunning {
+		t.Errorf("Expected running, got %v", status)
+	}
+	<-started
+
+	if err := tree.Halt(ctx); err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	select {
+	case err := <-cancelled:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Errorf("Running function was not cancelled when its node was halted")
+	}
+}
+
+func TestAsyncFunctionActionIgnoresStaleCompletion(t *testing.T) {
+	asyncFunctionAction := AsyncFunctionAction(AsyncFunctionActionParams{
+		BaseParams: "stale",
+		Func: func(ctx context.Context) core.ResultDetails {
+			return core.SuccessResult()
+		},
+	})
+
+	ctx := t.Context()
+	var events []core.Event
+	enqueue := func(evt core.Event) error {
+		events = append(events, evt)
+		return nil
+	}
+
+	first, ok := core.Update(ctx, asyncFunctionAction, core.DefaultEvent{}).(core.InitRunningResultDetails)
+	if !ok {
+		t.Fatalf("Expected InitRunningResultDetails")
+	}
+	if first.NodeId != asyncFunctionAction.Id() {
+		t.Errorf("Expected running function to be attributed to %v, got %v", asyncFunctionAction.Id(), first.NodeId)
+	}
+
+	// Complete the first activation's function, but only deliver the event
+	// after the node has been halted and activated again.
+	if err := first.RunningFn(ctx, enqueue); err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+	stale := events[0]
+
+	if err := core.Halt(ctx, asyncFunctionAction); err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+	second, ok := core.Update(ctx, asyncFunctionAction, core.DefaultEvent{}).(core.InitRunningResultDetails)
+	if !ok {
+		t.Fatalf("Expected InitRunningResultDetails")
+	}
+
+	if status := core.Update(ctx, asyncFunctionAction, stale).Status(); status != core.StatusRunning {
+		t.Errorf("Stale completion should be ignored, got %v", status)
+	}
+
+	// The first activation's function can no longer enqueue anything.
+	if err := first.RunningFn(ctx, enqueue); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	if err := second.RunningFn(ctx, enqueue); err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+	current := events[len(events)-1]
+	if status := core.Update(ctx, asyncFunctionAction, current).Status(); status != core.StatusSuccess {
+		t.Errorf("Expected success, got %v", status)
+	}
+}
@@ -2,6 +2,8 @@ package action
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 
 	"github.com/jbcpollak/greenstalk/v2/core"
 	"github.com/jbcpollak/greenstalk/v2/internal"
@@ -14,6 +16,13 @@ type CounterParams struct {
 	CountChan chan uint
 }
 
+func (p CounterParams) Validate(context.Context) error {
+	if p.CountChan == nil {
+		return errors.New("CountChan is nil")
+	}
+	return nil
+}
+
 // Counter increments a counter on the blackboard until it reaches a certain value.
 func Counter(params CounterParams) core.Node {
 	base := core.NewLeaf(params)
@@ -47,4 +56,26 @@ func (a *counter) Leave(context.Context) error {
 	return nil
 }
 
-var _ core.Node = (*counter)(nil)
+type counterState struct {
+	Count uint `json:"count"`
+}
+
+// SaveState saves the current count.
+func (a *counter) SaveState() (json.RawMessage, error) {
+	return json.Marshal(counterState{Count: a.currentValue})
+}
+
+// RestoreState restores the count saved by SaveState.
+func (a *counter) RestoreState(raw json.RawMessage) error {
+	var state counterState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return err
+	}
+	a.currentValue = state.Count
+	return nil
+}
+
+var (
+	_ core.Node     = (*counter)(nil)
+	_ core.Stateful = (*counter)(nil)
+)
@@ -42,4 +42,12 @@ func (a *fail) Leave(context.Context) error {
 	return nil
 }
 
-var _ core.Node = (*fail)(nil)
+// StandardNode implements core.Standard.
+func (a *fail) StandardNode() (string, []core.Port) {
+	return "AlwaysFailure", nil
+}
+
+var (
+	_ core.Node     = (*fail)(nil)
+	_ core.Standard = (*fail)(nil)
+)
@@ -2,6 +2,7 @@ package action
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/jbcpollak/greenstalk/v2/core"
@@ -12,6 +13,13 @@ type FunctionActionParams struct {
 	Func func() core.ResultDetails
 }
 
+func (p FunctionActionParams) Validate(context.Context) error {
+	if p.Func == nil {
+		return errors.New("Func is nil")
+	}
+	return nil
+}
+
 // FunctionAction executes the provided function when activated and returns its result. Note that the function is executed
 // synchronously so it must not block or the tree becomes unresponsive. Use AsyncFunctionAction for long running functions.
 func FunctionAction(params FunctionActionParams) *function_action {
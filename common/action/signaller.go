@@ -1,6 +1,9 @@
 package action
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/jbcpollak/greenstalk/v2/core"
 )
 
@@ -12,16 +15,29 @@ type SignallerParams[T any] struct {
 }
 
 // Sends a Signal on the provided channel
-func Signaller[T any](params SignallerParams[T]) *function_action {
-	fap := FunctionActionParams{
-		Func: func() core.ResultDetails {
-			// TODO: FunctionAction should pass some information to the function
-			// internal.Logger.Info("Signalling", "name", a.Name())
-
-			params.Channel <- params.Signal
-			return core.SuccessResult()
-		},
-	}
-	base := core.NewLeaf(fap)
-	return &function_action{Leaf: base}
+func Signaller[T any](params SignallerParams[T]) *signaller[T] {
+	base := core.NewLeaf(params)
+	return &signaller[T]{Leaf: base}
+}
+
+type signaller[T any] struct {
+	core.Leaf[SignallerParams[T]]
+}
+
+func (a *signaller[T]) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
+	a.Params.Channel <- a.Params.Signal
+	return core.SuccessResult()
 }
+
+func (a *signaller[T]) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
+	// Should never get here
+	return core.ErrorResult(
+		fmt.Errorf("Signaller node should not be ticked"),
+	)
+}
+
+func (a *signaller[T]) Leave(context.Context) error {
+	return nil
+}
+
+var _ core.Node = (*signaller[any])(nil)
@@ -43,4 +43,12 @@ func (a *succeed) Leave(context.Context) error {
 	return nil
 }
 
-var _ core.Node = (*succeed)(nil)
+// StandardNode implements core.Standard.
+func (a *succeed) StandardNode() (string, []core.Port) {
+	return "AlwaysSuccess", nil
+}
+
+var (
+	_ core.Node     = (*succeed)(nil)
+	_ core.Standard = (*succeed)(nil)
+)
@@ -2,6 +2,7 @@ package composite
 
 import (
 	"context"
+	"errors"
 
 	"github.com/jbcpollak/greenstalk/v2/core"
 )
@@ -29,16 +30,38 @@ func (s *activeSequence) Activate(ctx context.Context, evt core.Event) core.Resu
 
 func (s *activeSequence) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
 	for i := 0; i < len(s.Children); i++ {
-		result := core.Update(ctx, s.Children[i], evt)
+		child := s.Children[i]
+		result := core.Update(ctx, child, evt)
 		if result.Status() != core.StatusSuccess {
-			return result
+			// Later children may still be Running from a previous tick,
+			// but they are no longer reached so they must be halted.
+			if err := s.haltFrom(ctx, i+1); err != nil {
+				return core.ErrorResult(err)
+			}
+			return core.PropagateFailure(child, result)
 		}
 	}
 	return core.SuccessResult()
 }
 
+func (s *activeSequence) haltFrom(ctx context.Context, start int) error {
+	var errs []error
+	for _, child := range s.Children[start:] {
+		errs = append(errs, core.Halt(ctx, child))
+	}
+	return errors.Join(errs...)
+}
+
 func (s *activeSequence) Leave(context.Context) error {
 	return nil
 }
 
-var _ core.Node = (*activeSequence)(nil)
+// StandardNode implements core.Standard.
+func (s *activeSequence) StandardNode() (string, []core.Port) {
+	return "ReactiveSequence", nil
+}
+
+var (
+	_ core.Node     = (*activeSequence)(nil)
+	_ core.Standard = (*activeSequence)(nil)
+)
@@ -0,0 +1,161 @@
+package composite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jbcpollak/greenstalk/v2"
+	"github.com/jbcpollak/greenstalk/v2/common/action"
+	"github.com/jbcpollak/greenstalk/v2/common/decorator"
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+// runningLeaf stays Running until it is halted, and records its Leave calls.
+type runningLeaf struct {
+	core.Leaf[core.BaseParams]
+	leaves int
+}
+
+func (a *runningLeaf) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
+	return core.RunningResult()
+}
+
+func (a *runningLeaf) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
+	return core.RunningResult()
+}
+
+func (a *runningLeaf) Leave(context.Context) error {
+	a.leaves++
+	return nil
+}
+
+var _ core.Node = (*runningLeaf)(nil)
+
+func makeRunningLeaf(name string) *runningLeaf {
+	return &runningLeaf{Leaf: core.NewLeaf(core.BaseParams(name))}
+}
+
+func TestParallelHaltsRunningChildren(t *testing.T) {
+	running := makeRunningLeaf("running")
+
+	closeCalled := false
+	with := decorator.With(func(context.Context) (func(context.Context) error, error) {
+		return func(context.Context) error {
+			closeCalled = true
+			return nil
+		}, nil
+	}, running)
+
+	tree, err := greenstalk.NewBehaviorTree(
+		Parallel(1, 0,
+			action.Succeed(action.SucceedParams{BaseParams: "success"}),
+			with,
+		),
+	)
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	result := tree.Update(t.Context(), core.DefaultEvent{})
+	if result.Status() != core.StatusSuccess {
+		t.Errorf("Expected success, got %v", result.Status())
+	}
+
+	if running.leaves != 1 {
+		t.Errorf("Expected running child to be left once, got %d", running.leaves)
+	}
+	if status := running.Result().Status(); status != core.StatusInvalid {
+		t.Errorf("Expected halted child to be reset to invalid, got %v", status)
+	}
+	if status := with.Result().Status(); status != core.StatusInvalid {
+		t.Errorf("Expected halted decorator to be reset to invalid, got %v", status)
+	}
+	if !closeCalled {
+		t.Errorf("Expected With close function to be called on halt")
+	}
+}
+
+func TestErrorHaltsRunningChildren(t *testing.T) {
+	running := makeRunningLeaf("running")
+	erroring := action.FunctionAction(action.FunctionActionParams{
+		BaseParams: "erroring",
+		Func: func() core.ResultDetails {
+			return core.ErrorResult(errors.New("broken"))
+		},
+	})
+	root := Parallel(0, 0, running, erroring)
+
+	tree, err := greenstalk.NewBehaviorTree(root)
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	if status := tree.Update(t.Context(), core.DefaultEvent{}).Status(); status != core.StatusError {
+		t.Errorf("Expected error, got %v", status)
+	}
+	if running.leaves != 1 {
+		t.Errorf("Expected running child to be left once, got %d", running.leaves)
+	}
+	if status := running.Result().Status(); status != core.StatusInvalid {
+		t.Errorf("Expected halted child to be reset to invalid, got %v", status)
+	}
+}
+
+func TestActiveSequenceHaltsUnreachedChildren(t *testing.T) {
+	ready := false
+	guard := action.FunctionAction(action.FunctionActionParams{
+		BaseParams: "guard",
+		Func: func() core.ResultDetails {
+			if ready {
+				return core.SuccessResult()
+			}
+			return core.FailureResult()
+		},
+	})
+	running := makeRunningLeaf("running")
+
+	tree, err := greenstalk.NewBehaviorTree(ActiveSequence(guard, running))
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	ready = true
+	if status := tree.Update(t.Context(), core.DefaultEvent{}).Status(); status != core.StatusRunning {
+		t.Errorf("Expected running, got %v", status)
+	}
+
+	ready = false
+	if status := tree.Update(t.Context(), core.DefaultEvent{}).Status(); status != core.StatusFailure {
+		t.Errorf("Expected failure, got %v", status)
+	}
+
+	if running.leaves != 1 {
+		t.Errorf("Expected preempted child to be left once, got %d", running.leaves)
+	}
+	if status := running.Result().Status(); status != core.StatusInvalid {
+		t.Errorf("Expected preempted child to be reset to invalid, got %v", status)
+	}
+}
+
+func TestTreeHalt(t *testing.T) {
+	running := makeRunningLeaf("running")
+	root := Sequence(running)
+
+	tree, err := greenstalk.NewBehaviorTree(root)
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	tree.Update(t.Context(), core.DefaultEvent{})
+
+	if err := tree.Halt(t.Context()); err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+	if running.leaves != 1 {
+		t.Errorf("Expected running child to be left once, got %d", running.leaves)
+	}
+	if status := root.Result().Status(); status != core.StatusInvalid {
+		t.Errorf("Expected halted root to be reset to invalid, got %v", status)
+	}
+}
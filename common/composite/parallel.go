@@ -2,6 +2,10 @@ package composite
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
 
 	"github.com/jbcpollak/greenstalk/v2/core"
 )
@@ -92,7 +96,13 @@ func (s *parallel) Tick(ctx context.Context, evt core.Event) core.ResultDetails
 		return core.SuccessResult()
 	}
 	if s.failed >= s.failReq {
-		return core.FailureResult()
+		var failed []core.Walkable
+		for _, child := range s.Children {
+			if child.Result().Status() == core.StatusFailure {
+				failed = append(failed, child)
+			}
+		}
+		return core.FailureCausedBy(fmt.Sprintf("%d of %d children failed", s.failed, len(s.Children)), failed...)
 	}
 
 	if len(runningResultDetails) > 0 {
@@ -102,8 +112,72 @@ func (s *parallel) Tick(ctx context.Context, evt core.Event) core.ResultDetails
 	}
 }
 
+// Validate checks that the success and failure thresholds can be reached.
+func (s *parallel) Validate(ctx context.Context) error {
+	var errs []error
+	if s.successReq > len(s.Children) {
+		errs = append(errs, fmt.Errorf("success threshold %d exceeds child count %d", s.successReq, len(s.Children)))
+	}
+	if s.failReq > len(s.Children) {
+		errs = append(errs, fmt.Errorf("failure threshold %d exceeds child count %d", s.failReq, len(s.Children)))
+	}
+	errs = append(errs, s.Composite.Validate(ctx))
+	return errors.Join(errs...)
+}
+
 func (s *parallel) Leave(context.Context) error {
 	return nil
 }
 
-var _ core.Node = (*parallel)(nil)
+type parallelState struct {
+	Succeeded int    `json:"succeeded"`
+	Failed    int    `json:"failed"`
+	Completed []bool `json:"completed"`
+}
+
+// SaveState saves the counts and which children have completed.
+func (s *parallel) SaveState() (json.RawMessage, error) {
+	return json.Marshal(parallelState{Succeeded: s.succeeded, Failed: s.failed, Completed: s.completed})
+}
+
+// RestoreState restores the state saved by SaveState.
+func (s *parallel) RestoreState(raw json.RawMessage) error {
+	var state parallelState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return err
+	}
+	if len(state.Completed) != len(s.Children) {
+		return fmt.Errorf("expected %d completed flags, got %d", len(s.Children), len(state.Completed))
+	}
+	s.succeeded, s.failed = state.Succeeded, state.Failed
+	copy(s.completed, state.Completed)
+	return nil
+}
+
+// InitInstance gives a clone its own completion flags.
+func (s *parallel) InitInstance() {
+	s.completed = make([]bool, len(s.Children))
+}
+
+// StandardNode implements core.Standard.
+func (s *parallel) StandardNode() (string, []core.Port) {
+	// The thresholds are resolved by the constructor, where every child
+	// is -1 in BehaviorTree.CPP.
+	threshold := func(n int) string {
+		if n == len(s.Children) {
+			return "-1"
+		}
+		return strconv.Itoa(n)
+	}
+	return "Parallel", []core.Port{
+		{Name: "success_count", Value: threshold(s.successReq)},
+		{Name: "failure_count", Value: threshold(s.failReq)},
+	}
+}
+
+var (
+	_ core.Node         = (*parallel)(nil)
+	_ core.Stateful     = (*parallel)(nil)
+	_ core.Instantiable = (*parallel)(nil)
+	_ core.Standard     = (*parallel)(nil)
+)
@@ -28,9 +28,10 @@ func (s *persistentSequence) Activate(ctx context.Context, evt core.Event) core.
 
 func (s *persistentSequence) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
 	for s.CurrentChild < len(s.Children) {
-		result := core.Update(ctx, s.Children[s.CurrentChild], evt)
+		child := s.Children[s.CurrentChild]
+		result := core.Update(ctx, child, evt)
 		if result.Status() != core.StatusSuccess {
-			return result
+			return core.PropagateFailure(child, result)
 		}
 		s.CurrentChild++
 	}
@@ -42,4 +43,12 @@ func (s *persistentSequence) Leave(context.Context) error {
 	return nil
 }
 
-var _ core.Node = (*persistentSequence)(nil)
+// StandardNode implements core.Standard.
+func (s *persistentSequence) StandardNode() (string, []core.Port) {
+	return "SequenceWithMemory", nil
+}
+
+var (
+	_ core.Node     = (*persistentSequence)(nil)
+	_ core.Standard = (*persistentSequence)(nil)
+)
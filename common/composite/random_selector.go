@@ -31,7 +31,7 @@ func (s *randomSelector) Activate(ctx context.Context, evt core.Event) core.Resu
 func (s *randomSelector) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
 	index := rand.Intn(len(s.Children))
 	child := s.Children[index]
-	return core.Update(ctx, child, evt)
+	return core.PropagateFailure(child, core.Update(ctx, child, evt))
 }
 
 // Leave ...
@@ -2,6 +2,8 @@ package composite
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"math/rand"
 
 	"github.com/jbcpollak/greenstalk/v2/core"
@@ -20,19 +22,22 @@ func RandomSequence(children ...core.Node) core.Node {
 
 type randomSequence struct {
 	core.Composite[core.BaseParams]
+	// order holds the indices of the children in the order they run in.
+	order []int
 }
 
 func (s *randomSequence) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
-	shuffle(s.Children)
+	s.order = rand.Perm(len(s.Children))
 
 	return s.Tick(ctx, evt)
 }
 
 func (s *randomSequence) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
 	for s.CurrentChild < len(s.Children) {
-		result := core.Update(ctx, s.Children[s.CurrentChild], evt)
+		child := s.Children[s.order[s.CurrentChild]]
+		result := core.Update(ctx, child, evt)
 		if result.Status() != core.StatusSuccess {
-			return result
+			return core.PropagateFailure(child, result)
 		}
 		s.CurrentChild++
 	}
@@ -44,10 +49,37 @@ func (s *randomSequence) Leave(context.Context) error {
 	return nil
 }
 
-func shuffle(nodes []core.Node) {
-	rand.Shuffle(len(nodes), func(i, j int) {
-		nodes[i], nodes[j] = nodes[j], nodes[i]
-	})
+// SaveState saves the order the children were shuffled into, as their
+// indices.
+func (s *randomSequence) SaveState() (json.RawMessage, error) {
+	return json.Marshal(s.order)
 }
 
-var _ core.Node = (*randomSequence)(nil)
+// RestoreState shuffles the children back into the saved order.
+func (s *randomSequence) RestoreState(raw json.RawMessage) error {
+	var order []int
+	if err := json.Unmarshal(raw, &order); err != nil {
+		return err
+	}
+	if order == nil {
+		// The node has never been activated.
+		return nil
+	}
+	if len(order) != len(s.Children) {
+		return fmt.Errorf("expected %d children, got %d", len(s.Children), len(order))
+	}
+	seen := make([]bool, len(order))
+	for _, i := range order {
+		if i < 0 || i >= len(order) || seen[i] {
+			return fmt.Errorf("invalid or duplicate child index %d", i)
+		}
+		seen[i] = true
+	}
+	s.order = order
+	return nil
+}
+
+var (
+	_ core.Node     = (*randomSequence)(nil)
+	_ core.Stateful = (*randomSequence)(nil)
+)
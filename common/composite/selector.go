@@ -37,11 +37,27 @@ func (s *selector) Tick(ctx context.Context, evt core.Event) core.ResultDetails
 		}
 		s.CurrentChild++
 	}
-	return core.FailureResult()
+	return core.FailureCausedBy("all children failed", walkables(s.Children)...)
 }
 
 func (s *selector) Leave(context.Context) error {
 	return nil
 }
 
-var _ core.Node = (*selector)(nil)
+func walkables(nodes []core.Node) []core.Walkable {
+	walkables := make([]core.Walkable, len(nodes))
+	for i, node := range nodes {
+		walkables[i] = node
+	}
+	return walkables
+}
+
+// StandardNode implements core.Standard.
+func (s *selector) StandardNode() (string, []core.Port) {
+	return "Fallback", nil
+}
+
+var (
+	_ core.Node     = (*selector)(nil)
+	_ core.Standard = (*selector)(nil)
+)
@@ -31,9 +31,10 @@ func (s *sequence) Activate(ctx context.Context, evt core.Event) core.ResultDeta
 
 func (s *sequence) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
 	for s.CurrentChild < len(s.Children) {
-		result := core.Update(ctx, s.Children[s.CurrentChild], evt)
+		child := s.Children[s.CurrentChild]
+		result := core.Update(ctx, child, evt)
 		if result.Status() != core.StatusSuccess {
-			return result
+			return core.PropagateFailure(child, result)
 		}
 		s.CurrentChild++
 	}
@@ -44,4 +45,12 @@ func (s *sequence) Leave(context.Context) error {
 	return nil
 }
 
-var _ core.Node = (*sequence)(nil)
+// StandardNode implements core.Standard.
+func (s *sequence) StandardNode() (string, []core.Port) {
+	return "Sequence", nil
+}
+
+var (
+	_ core.Node     = (*sequence)(nil)
+	_ core.Standard = (*sequence)(nil)
+)
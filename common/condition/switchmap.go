@@ -3,9 +3,12 @@ package condition
 import (
 	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"maps"
 	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/jbcpollak/greenstalk/v2/core"
 )
@@ -52,11 +55,40 @@ func (i *switchMapNode[T]) Activate(ctx context.Context, evt core.Event) core.Re
 
 func (s *switchMapNode[T]) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
 	child := s.Children[s.CurrentChild]
-	return core.Update(ctx, child, evt)
+	return core.PropagateFailure(child, core.Update(ctx, child, evt))
+}
+
+// Validate checks that there is a switch function to pick a child with.
+func (s *switchMapNode[T]) Validate(ctx context.Context) error {
+	var errs []error
+	if s.switchFunc == nil {
+		errs = append(errs, errors.New("switch function is nil"))
+	}
+	errs = append(errs, s.Composite.Validate(ctx))
+	return errors.Join(errs...)
 }
 
 func (s *switchMapNode[T]) Leave(context.Context) error {
 	return nil
 }
 
-var _ core.Node = (*switchMapNode[int])(nil)
+// CustomNode implements core.Custom, writing the node as a Switch if its
+// keys are the indices of its children, and as a SwitchMap with its keys
+// separated by semicolons otherwise.
+func (s *switchMapNode[T]) CustomNode() (string, []core.Port, []core.Node) {
+	keys := make([]string, len(s.Children))
+	indices := true
+	for key, i := range s.childrenIndices {
+		keys[i] = fmt.Sprint(key)
+		indices = indices && keys[i] == strconv.Itoa(i)
+	}
+	if _, ok := any(*new(T)).(int); ok && indices {
+		return "Switch", nil, s.Children
+	}
+	return "SwitchMap", []core.Port{{Name: "keys", Value: strings.Join(keys, ";")}}, s.Children
+}
+
+var (
+	_ core.Node   = (*switchMapNode[int])(nil)
+	_ core.Custom = (*switchMapNode[int])(nil)
+)
@@ -2,7 +2,9 @@ package decorator
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,6 +18,13 @@ type AsyncDelayerParams struct {
 	Delay time.Duration
 }
 
+func (p AsyncDelayerParams) Validate(context.Context) error {
+	if p.Delay < 0 {
+		return fmt.Errorf("negative delay %v", p.Delay)
+	}
+	return nil
+}
+
 // AsyncDelayer ...
 func AsyncDelayer(params AsyncDelayerParams, child core.Node) core.Node {
 	base := core.NewDecorator(params, child)
@@ -32,10 +41,14 @@ type asyncdelayer struct {
 	core.Decorator[AsyncDelayerParams]
 	delay time.Duration // delay in milliseconds
 	start time.Time
+	// resume is set when the node is restored from a checkpoint, and
+	// its timer must be started again.
+	resume bool
 }
 
 type DelayerFinishedEvent struct {
 	targetNodeId uuid.UUID
+	generation   uint64
 	start        time.Time
 }
 
@@ -43,33 +56,55 @@ func (e DelayerFinishedEvent) TargetNodeId() uuid.UUID {
 	return e.targetNodeId
 }
 
-func (d *asyncdelayer) doDelay(ctx context.Context, enqueue core.EnqueueFn) error {
-	t := time.NewTimer(d.delay)
+func (e DelayerFinishedEvent) ControlEvent() {}
+
+func (d *asyncdelayer) doDelay(ctx context.Context, enqueue core.EnqueueFn, delay time.Duration, generation uint64, start time.Time) error {
+	t := time.NewTimer(delay)
 	defer t.Stop()
 	select {
 	case <-ctx.Done():
 		return fmt.Errorf("async delay interrupted: %w", ctx.Err())
 	case <-t.C:
-		internal.Logger.DebugContext(ctx, "Delay Duration", "duration", time.Since(d.start))
-		return enqueue(DelayerFinishedEvent{d.Id(), d.start})
+		internal.Logger.DebugContext(ctx, "Delay Duration", "duration", time.Since(start))
+		return enqueue(DelayerFinishedEvent{d.Id(), generation, start})
 	}
 }
 
 // Activate ...
 func (d *asyncdelayer) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
 	d.start = time.Now()
+	d.resume = false
 
 	internal.Logger.DebugContext(ctx, "Returning AsyncRunning", "name", d.Name())
 
-	return core.InitRunningResult(d.doDelay)
+	return d.wait(d.delay)
+}
+
+func (d *asyncdelayer) wait(delay time.Duration) core.ResultDetails {
+	generation, start := d.Generation(), d.start
+	return core.InitRunningResult(func(ctx context.Context, enqueue core.EnqueueFn) error {
+		return d.doDelay(ctx, enqueue, delay, generation, start)
+	})
 }
 
 // Tick ...
 func (d *asyncdelayer) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
 	internal.Logger.DebugContext(ctx, "Tick", "name", d.Name())
 
+	// Once the delay has elapsed, events belong to the child.
+	if d.Child.Result().Status() == core.StatusRunning {
+		d.resume = false
+		return core.Update(ctx, d.Child, evt)
+	}
+
+	if d.resume {
+		d.resume = false
+		return d.wait(max(d.delay-time.Since(d.start), 0))
+	}
+
 	if dfe, ok := evt.(DelayerFinishedEvent); ok {
-		if dfe.TargetNodeId() == d.Id() {
+		// Ignore completions left over from an earlier activation.
+		if dfe.TargetNodeId() == d.Id() && dfe.generation == d.Generation() {
 			internal.Logger.DebugContext(ctx, "DelayerFinishedEvent", "name", d.Name())
 			return core.Update(ctx, d.Child, evt)
 		}
@@ -83,4 +118,31 @@ func (d *asyncdelayer) Leave(context.Context) error {
 	return nil
 }
 
-var _ core.Node = (*asyncdelayer)(nil)
+// SaveState saves when the delay started.
+func (d *asyncdelayer) SaveState() (json.RawMessage, error) {
+	return json.Marshal(delayerState{Start: d.start})
+}
+
+// RestoreState restores the start of the delay. If the delay had not
+// elapsed, the timer is started again for the rest of it on the next
+// Tick.
+func (d *asyncdelayer) RestoreState(raw json.RawMessage) error {
+	var state delayerState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return err
+	}
+	d.start = state.Start
+	d.resume = d.Result().Status() == core.StatusRunning
+	return nil
+}
+
+// StandardNode implements core.Standard.
+func (d *asyncdelayer) StandardNode() (string, []core.Port) {
+	return "Delay", []core.Port{{Name: "delay_msec", Value: strconv.FormatInt(d.Params.Delay.Milliseconds(), 10)}}
+}
+
+var (
+	_ core.Node     = (*asyncdelayer)(nil)
+	_ core.Stateful = (*asyncdelayer)(nil)
+	_ core.Standard = (*asyncdelayer)(nil)
+)
@@ -0,0 +1,61 @@
+package decorator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jbcpollak/greenstalk/v2"
+	"github.com/jbcpollak/greenstalk/v2/common/action"
+	"github.com/jbcpollak/greenstalk/v2/core"
+	"github.com/jbcpollak/greenstalk/v2/queue"
+)
+
+// awaitEvent pops the next event of q, waiting up to a second for it.
+func awaitEvent(t *testing.T, q queue.EventQueue) core.Event {
+	t.Helper()
+	select {
+	case <-q.Ready():
+	case <-time.After(time.Second):
+		t.Fatalf("No event was queued")
+	}
+	evt, ok := q.Pop()
+	if !ok {
+		t.Fatalf("No event was queued")
+	}
+	return evt
+}
+
+// TestAsyncDelayerEventsKept checks that the event completing the delay
+// survives queue policies that drop events.
+func TestAsyncDelayerEventsKept(t *testing.T) {
+	cases := map[string]queue.EventQueue{
+		"drop oldest": queue.NewBounded(1, queue.DropOldest),
+		"ttl":         queue.New(queue.WithTTL(time.Millisecond)),
+	}
+	for name, q := range cases {
+		tree, err := greenstalk.NewBehaviorTree(
+			AsyncDelayer(AsyncDelayerParams{BaseParams: "Delayer", Delay: time.Millisecond}, action.Succeed(action.SucceedParams{})),
+			greenstalk.WithEventQueue(q),
+		)
+		if err != nil {
+			t.Fatalf("%s: unexpectedly got %v", name, err)
+		}
+
+		if result := tree.Update(t.Context(), core.DefaultEvent{}); result.Status() != core.StatusRunning {
+			t.Fatalf("%s: expected the delayer to run, got %v", name, result)
+		}
+		// Let the event outlive the TTL, and try to push it out.
+		time.Sleep(20 * time.Millisecond)
+		if err := q.Push(t.Context(), core.DefaultEvent{}); err != nil {
+			t.Fatalf("%s: unexpectedly got %v", name, err)
+		}
+
+		evt := awaitEvent(t, q)
+		if _, ok := evt.(DelayerFinishedEvent); !ok {
+			t.Fatalf("%s: expected the delayer's event, got %#v", name, evt)
+		}
+		if result := tree.Update(t.Context(), evt); result.Status() != core.StatusSuccess {
+			t.Errorf("%s: expected the delayer to complete, got %v", name, result)
+		}
+	}
+}
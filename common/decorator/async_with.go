@@ -2,6 +2,10 @@ package decorator
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
 
 	"github.com/jbcpollak/greenstalk/v2/common/action"
 	"github.com/jbcpollak/greenstalk/v2/core"
@@ -17,6 +21,10 @@ func WithAsyncNamed(
 		BaseParams: core.BaseParams("enter"),
 		Func: func(ctx context.Context) core.ResultDetails {
 			err := enterFunc(ctx)
+			if e, ok := ctx.Value(enteringKey{}).(*entering); ok && e.finish(err == nil) {
+				// Halted while entering: release whatever was acquired.
+				err = errors.Join(err, exitFunc(context.WithoutCancel(ctx)))
+			}
 			if err != nil {
 				return core.ErrorResult(err)
 			}
@@ -27,7 +35,8 @@ func WithAsyncNamed(
 	exitNode := action.AsyncFunctionAction(action.AsyncFunctionActionParams{
 		BaseParams: core.BaseParams("exit"),
 		Func: func(ctx context.Context) core.ResultDetails {
-			err := exitFunc(ctx)
+			// Teardown is not interrupted when the node is halted.
+			err := exitFunc(context.WithoutCancel(ctx))
 			if err != nil {
 				return core.ErrorResult(err)
 			}
@@ -36,7 +45,7 @@ func WithAsyncNamed(
 	})
 
 	base := core.NewComposite(core.BaseParams(name), []core.Node{enterNode, child, exitNode})
-	return &asyncWithSequence{Composite: base}
+	return &asyncWithSequence{Composite: base, exitFunc: exitFunc}
 
 }
 
@@ -52,19 +61,64 @@ func WithAsync(
 // returns the status of the second child
 type asyncWithSequence struct {
 	core.Composite[core.BaseParams]
-	result core.ResultDetails
+	exitFunc func(context.Context) error
+	result   core.ResultDetails
+	// entering is the state of the enter function of the current
+	// activation.
+	entering *entering
+}
+
+// entering tracks the enter function of one activation, so that halting
+// the node while it runs still releases what it acquired. It is shared
+// with the enter node's RunningFn through the context.
+type entering struct {
+	mu      sync.Mutex
+	entered bool
+	halted  bool
+}
+
+type enteringKey struct{}
+
+// finish records that the enter function returned, and whether it
+// succeeded. It reports whether the node was halted meanwhile, in which
+// case the caller must run the exit function.
+func (e *entering) finish(ok bool) (halted bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.halted {
+		return true
+	}
+	e.entered = ok
+	return false
+}
+
+// halt records that the node was halted before its enter node completed.
+// It reports whether the enter function had already succeeded, in which
+// case the caller must run the exit function.
+func (e *entering) halt() (entered bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.halted = true
+	return e.entered
 }
 
 func (s *asyncWithSequence) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
 	if len(s.Children) != 3 {
-		panic("asyncWithSequence must have exactly 3 children")
+		return core.ErrorResult(fmt.Errorf("asyncWithSequence must have exactly 3 children, got %d", len(s.Children)))
 	}
 	s.CurrentChild = 0
+	s.entering = &entering{}
 
 	return s.Tick(ctx, evt)
 }
 
 func (s *asyncWithSequence) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
+	// A restored node is ticked without being activated.
+	if s.entering == nil {
+		s.entering = &entering{}
+	}
+	ctx = context.WithValue(ctx, enteringKey{}, s.entering)
+
 	for s.CurrentChild < len(s.Children) {
 		result := core.Update(ctx, s.Children[s.CurrentChild], evt)
 		if result.Status() == core.StatusRunning || result.Status() == core.StatusError {
@@ -72,15 +126,89 @@ func (s *asyncWithSequence) Tick(ctx context.Context, evt core.Event) core.Resul
 		}
 
 		if s.CurrentChild == 1 {
-			s.result = result
+			s.result = core.PropagateFailure(s.Children[1], result)
 		}
 		s.CurrentChild++
 	}
 	return s.result
 }
 
+// Halt interrupts the wrapped child. If the enter function has already
+// completed, the exit function is run synchronously so that whatever was
+// set up is torn down even though the exit node will never be reached. If
+// the enter function is still running, it runs the exit function itself
+// once it returns. A running exit function is left to complete.
+func (s *asyncWithSequence) Halt(ctx context.Context) error {
+	var exit bool
+	switch s.CurrentChild {
+	case 0:
+		exit = s.entering != nil && s.entering.halt()
+	case 1:
+		exit = true
+	}
+	err := s.Composite.Halt(ctx)
+	if exit {
+		err = errors.Join(err, s.exitFunc(ctx))
+	}
+	return err
+}
+
+// Validate checks the enter, child, exit structure and the exit function.
+func (s *asyncWithSequence) Validate(ctx context.Context) error {
+	var errs []error
+	if len(s.Children) != 3 {
+		errs = append(errs, fmt.Errorf("asyncWithSequence must have exactly 3 children, got %d", len(s.Children)))
+	}
+	if s.exitFunc == nil {
+		errs = append(errs, errors.New("exit function is nil"))
+	}
+	errs = append(errs, s.Composite.Validate(ctx))
+	return errors.Join(errs...)
+}
+
 func (s *asyncWithSequence) Leave(context.Context) error {
 	return nil
 }
 
-var _ core.Node = (*asyncWithSequence)(nil)
+// SaveState saves the status of the wrapped child, which is returned once
+// the exit function has run.
+func (s *asyncWithSequence) SaveState() (json.RawMessage, error) {
+	if s.result == nil {
+		return nil, nil
+	}
+	return json.Marshal(s.result.Status())
+}
+
+// RestoreState restores the status saved by SaveState.
+func (s *asyncWithSequence) RestoreState(raw json.RawMessage) error {
+	s.result = nil
+	if raw == nil {
+		return nil
+	}
+	var status core.Status
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return err
+	}
+	switch status {
+	case core.StatusSuccess:
+		s.result = core.SuccessResult()
+	case core.StatusFailure:
+		s.result = core.FailureResult()
+	}
+	return nil
+}
+
+// CustomNode implements core.Custom, writing the node as the WithAsync
+// decorator it was built with.
+func (s *asyncWithSequence) CustomNode() (string, []core.Port, []core.Node) {
+	if len(s.Children) != 3 {
+		return "WithAsync", nil, nil
+	}
+	return "WithAsync", nil, s.Children[1:2]
+}
+
+var (
+	_ core.Node     = (*asyncWithSequence)(nil)
+	_ core.Stateful = (*asyncWithSequence)(nil)
+	_ core.Custom   = (*asyncWithSequence)(nil)
+)
@@ -2,6 +2,7 @@ package decorator
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
@@ -12,6 +13,7 @@ import (
 	"github.com/jbcpollak/greenstalk/v2/common/composite"
 	"github.com/jbcpollak/greenstalk/v2/core"
 	"github.com/jbcpollak/greenstalk/v2/internal"
+	"github.com/jbcpollak/greenstalk/v2/queue"
 	"github.com/jbcpollak/greenstalk/v2/util"
 )
 
@@ -239,9 +241,10 @@ func TestWithAsyncEnterError(t *testing.T) {
 	evt := core.DefaultEvent{}
 	wg.Go(func() {
 		err = tree.EventLoop(ctx, evt)
+		var nodeErr *core.NodeError
 		if err == nil {
 			t.Error("Should have errored here")
-		} else if err.Error() != "This is an error" {
+		} else if !errors.As(err, &nodeErr) || nodeErr.Err.Error() != "This is an error" {
 			t.Errorf("Error does not have correct contents: %v", err)
 		}
 	})
@@ -361,3 +364,110 @@ func TestWithAsyncChildFailure(t *testing.T) {
 		t.Errorf("Exit was not called")
 	}
 }
+
+// TestWithAsyncHaltDuringExit checks that halting the node while the exit
+// function runs leaves it to complete.
+func TestWithAsyncHaltDuringExit(t *testing.T) {
+	exitStarted := make(chan struct{})
+	release := make(chan struct{})
+	exitDone := make(chan error, 1)
+	exitFunc := func(ctx context.Context) error {
+		close(exitStarted)
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		exitDone <- ctx.Err()
+		return nil
+	}
+
+	q := queue.New()
+	tree, err := greenstalk.NewBehaviorTree(
+		WithAsync(func(context.Context) error { return nil }, exitFunc, action.Succeed(action.SucceedParams{})),
+		greenstalk.WithEventQueue(q),
+	)
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	// Drive the tree until the exit node is running.
+	tree.Update(t.Context(), core.DefaultEvent{})
+	for started := false; !started; {
+		select {
+		case <-exitStarted:
+			started = true
+		case <-q.Ready():
+			for evt, ok := q.Pop(); ok; evt, ok = q.Pop() {
+				if result := tree.Update(t.Context(), evt); result.Status() != core.StatusRunning {
+					t.Fatalf("Expected the node to run, got %v", result)
+				}
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Exit was not started")
+		}
+	}
+
+	if err := tree.Halt(t.Context()); err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+	time.AfterFunc(20*time.Millisecond, func() { close(release) })
+
+	select {
+	case err := <-exitDone:
+		if err != nil {
+			t.Errorf("Exit was interrupted: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Exit did not complete")
+	}
+}
+
+// TestWithAsyncHaltDuringEnter checks that halting the node while the
+// enter function runs still calls the exit function once it returns.
+func TestWithAsyncHaltDuringEnter(t *testing.T) {
+	enterStarted := make(chan struct{})
+	release := make(chan struct{})
+	enterFunc := func(context.Context) error {
+		close(enterStarted)
+		<-release
+		return nil
+	}
+	exitCalled := make(chan struct{}, 2)
+	exitFunc := func(context.Context) error {
+		exitCalled <- struct{}{}
+		return nil
+	}
+
+	tree, err := greenstalk.NewBehaviorTree(
+		WithAsync(enterFunc, exitFunc, action.Succeed(action.SucceedParams{})),
+		greenstalk.WithEventQueue(queue.New()),
+	)
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	if result := tree.Update(t.Context(), core.DefaultEvent{}); result.Status() != core.StatusRunning {
+		t.Fatalf("Expected the node to run, got %v", result)
+	}
+	<-enterStarted
+	if err := tree.Halt(t.Context()); err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+	select {
+	case <-exitCalled:
+		t.Fatalf("Exit was called before enter returned")
+	default:
+	}
+	close(release)
+
+	select {
+	case <-exitCalled:
+	case <-time.After(time.Second):
+		t.Fatalf("Exit was not called")
+	}
+	select {
+	case <-exitCalled:
+		t.Errorf("Exit was called twice")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
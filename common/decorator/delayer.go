@@ -2,6 +2,9 @@ package decorator
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/jbcpollak/greenstalk/v2/core"
@@ -13,6 +16,13 @@ type DelayerParams struct {
 	Delay time.Duration
 }
 
+func (p DelayerParams) Validate(context.Context) error {
+	if p.Delay < 0 {
+		return fmt.Errorf("negative delay %v", p.Delay)
+	}
+	return nil
+}
+
 // Delayer ...
 func Delayer(params DelayerParams, child core.Node) core.Node {
 	base := core.NewDecorator(params, child)
@@ -51,4 +61,33 @@ func (d *delayer) Leave(context.Context) error {
 	return nil
 }
 
-var _ core.Node = (*delayer)(nil)
+type delayerState struct {
+	Start time.Time `json:"start"`
+}
+
+// SaveState saves when the delay started.
+func (d *delayer) SaveState() (json.RawMessage, error) {
+	return json.Marshal(delayerState{Start: d.start})
+}
+
+// RestoreState restores the start of the delay, so the time spent
+// between the checkpoint and the restore counts towards it.
+func (d *delayer) RestoreState(raw json.RawMessage) error {
+	var state delayerState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return err
+	}
+	d.start = state.Start
+	return nil
+}
+
+// StandardNode implements core.Standard.
+func (d *delayer) StandardNode() (string, []core.Port) {
+	return "Delay", []core.Port{{Name: "delay_msec", Value: strconv.FormatInt(d.Params.Delay.Milliseconds(), 10)}}
+}
+
+var (
+	_ core.Node     = (*delayer)(nil)
+	_ core.Stateful = (*delayer)(nil)
+	_ core.Standard = (*delayer)(nil)
+)
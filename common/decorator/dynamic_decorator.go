@@ -24,8 +24,7 @@ func (d *dynamicDecorator) Activate(ctx context.Context, evt core.Event) core.Re
 	if err != nil {
 		return core.ErrorResult(err)
 	}
-	child.SetNamePrefix(d.FullName())
-	d.Child = child
+	d.SetChild(ctx, child)
 
 	return d.Tick(ctx, evt)
 }
@@ -38,4 +37,13 @@ func (d *dynamicDecorator) Leave(context.Context) error {
 	return nil
 }
 
-var _ core.Node = (*dynamicDecorator)(nil)
+// CustomNode implements core.Custom. The child is built on activation, so
+// it is not written.
+func (d *dynamicDecorator) CustomNode() (string, []core.Port, []core.Node) {
+	return "DynamicDecorator", nil, nil
+}
+
+var (
+	_ core.Node   = (*dynamicDecorator)(nil)
+	_ core.Custom = (*dynamicDecorator)(nil)
+)
@@ -29,7 +29,7 @@ func (d *inverter) Activate(ctx context.Context, evt core.Event) core.ResultDeta
 func (d *inverter) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
 	switch result := core.Update(ctx, d.Child, evt); result.Status() {
 	case core.StatusSuccess:
-		return core.FailureResult()
+		return core.FailureCausedBy("child "+d.Child.Name()+" succeeded", d.Child)
 	case core.StatusFailure:
 		return core.SuccessResult()
 	default:
@@ -42,4 +42,12 @@ func (d *inverter) Leave(context.Context) error {
 	return nil
 }
 
-var _ core.Node = (*inverter)(nil)
+// StandardNode implements core.Standard.
+func (d *inverter) StandardNode() (string, []core.Port) {
+	return "Inverter", nil
+}
+
+var (
+	_ core.Node     = (*inverter)(nil)
+	_ core.Standard = (*inverter)(nil)
+)
@@ -0,0 +1,90 @@
+package decorator
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/jbcpollak/greenstalk/v2/core"
+	"github.com/jbcpollak/greenstalk/v2/internal"
+)
+
+type RepeatParams struct {
+	core.BaseParams
+
+	// Cycles is the number of times the child must succeed. A negative
+	// value repeats forever.
+	Cycles int
+}
+
+// Repeat updates its child until it has succeeded params.Cycles times, at
+// which point it returns Success. It fails as soon as the child fails.
+func Repeat(params RepeatParams, child core.Node) core.Node {
+	base := core.NewDecorator(params, child)
+	return &repeat{Decorator: base}
+}
+
+type repeat struct {
+	core.Decorator[RepeatParams]
+	succeeded int
+}
+
+func (d *repeat) again(ctx context.Context, enqueue core.EnqueueFn) error {
+	internal.Logger.DebugContext(ctx, "Repeating", "name", d.Name(), "succeeded", d.succeeded)
+	return enqueue(core.ResumeEvent(d.Id()))
+}
+
+func (d *repeat) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
+	d.succeeded = 0
+	if d.Params.Cycles == 0 {
+		return core.SuccessResult()
+	}
+	return d.Tick(ctx, evt)
+}
+
+func (d *repeat) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
+	result := core.Update(ctx, d.Child, evt)
+	if result.Status() != core.StatusSuccess {
+		return core.PropagateFailure(d.Child, result)
+	}
+
+	d.succeeded++
+	if d.Params.Cycles > 0 && d.succeeded >= d.Params.Cycles {
+		return core.SuccessResult()
+	}
+	return core.InitRunningResult(d.again)
+}
+
+func (d *repeat) Leave(context.Context) error {
+	return nil
+}
+
+type repeatState struct {
+	Succeeded int `json:"succeeded"`
+}
+
+// SaveState saves how often the child has succeeded so far.
+func (d *repeat) SaveState() (json.RawMessage, error) {
+	return json.Marshal(repeatState{Succeeded: d.succeeded})
+}
+
+// RestoreState restores the count saved by SaveState.
+func (d *repeat) RestoreState(raw json.RawMessage) error {
+	var state repeatState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return err
+	}
+	d.succeeded = state.Succeeded
+	return nil
+}
+
+// StandardNode implements core.Standard.
+func (d *repeat) StandardNode() (string, []core.Port) {
+	return "Repeat", []core.Port{{Name: "num_cycles", Value: strconv.Itoa(d.Params.Cycles)}}
+}
+
+var (
+	_ core.Node     = (*repeat)(nil)
+	_ core.Stateful = (*repeat)(nil)
+	_ core.Standard = (*repeat)(nil)
+)
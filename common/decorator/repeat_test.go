@@ -0,0 +1,84 @@
+package decorator
+
+import (
+	"testing"
+
+	"github.com/jbcpollak/greenstalk/v2"
+	"github.com/jbcpollak/greenstalk/v2/common/action"
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+// countingAction returns the results in order, one per activation, and
+// counts how often it ran.
+func countingAction(runs *int, results ...core.ResultDetails) core.Node {
+	return action.FunctionAction(action.FunctionActionParams{
+		BaseParams: "Counting",
+		Func: func() core.ResultDetails {
+			result := results[min(*runs, len(results)-1)]
+			*runs++
+			return result
+		},
+	})
+}
+
+func TestRepeat(t *testing.T) {
+	cases := []struct {
+		name     string
+		cycles   int
+		results  []core.ResultDetails
+		expected core.Status
+		runs     int
+	}{
+		{"succeeds after cycles", 3, []core.ResultDetails{core.SuccessResult()}, core.StatusSuccess, 3},
+		{"fails on first failure", 3, []core.ResultDetails{core.SuccessResult(), core.FailureResult()}, core.StatusFailure, 2},
+		{"zero cycles", 0, []core.ResultDetails{core.FailureResult()}, core.StatusSuccess, 0},
+	}
+	for _, c := range cases {
+		runs := 0
+		tree, err := greenstalk.NewBehaviorTree(
+			Repeat(RepeatParams{BaseParams: "Repeat", Cycles: c.cycles}, countingAction(&runs, c.results...)),
+		)
+		if err != nil {
+			t.Fatalf("Unexpectedly got %v", err)
+		}
+
+		result, err := tree.RunToCompletion(t.Context(), core.DefaultEvent{})
+		if err != nil {
+			t.Fatalf("%s: unexpectedly got %v", c.name, err)
+		}
+		if result.Status() != c.expected || runs != c.runs {
+			t.Errorf("%s: expected %v after %d runs, got %v after %d", c.name, c.expected, c.runs, result.Status(), runs)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	cases := []struct {
+		name     string
+		attempts int
+		results  []core.ResultDetails
+		expected core.Status
+		runs     int
+	}{
+		{"succeeds eventually", 3, []core.ResultDetails{core.FailureResult(), core.SuccessResult()}, core.StatusSuccess, 2},
+		{"gives up", 3, []core.ResultDetails{core.FailureResult()}, core.StatusFailure, 3},
+		{"retries forever", -1, []core.ResultDetails{core.FailureResult(), core.FailureResult(), core.FailureResult(), core.FailureResult(), core.SuccessResult()}, core.StatusSuccess, 5},
+	}
+	for _, c := range cases {
+		runs := 0
+		tree, err := greenstalk.NewBehaviorTree(
+			Retry(RetryParams{BaseParams: "Retry", Attempts: c.attempts}, countingAction(&runs, c.results...)),
+		)
+		if err != nil {
+			t.Fatalf("Unexpectedly got %v", err)
+		}
+
+		result, err := tree.RunToCompletion(t.Context(), core.DefaultEvent{})
+		if err != nil {
+			t.Fatalf("%s: unexpectedly got %v", c.name, err)
+		}
+		if result.Status() != c.expected || runs != c.runs {
+			t.Errorf("%s: expected %v after %d runs, got %v after %d", c.name, c.expected, c.runs, result.Status(), runs)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package decorator
 
 import (
 	"context"
+	"errors"
 
 	"github.com/jbcpollak/greenstalk/v2/core"
 	"github.com/jbcpollak/greenstalk/v2/internal"
@@ -16,6 +17,13 @@ type (
 	}
 )
 
+func (p RepeatUntilParams) Validate(context.Context) error {
+	if p.Until == nil {
+		return errors.New("Until condition is nil")
+	}
+	return nil
+}
+
 // RepeatUntil updates its child n times, at which point the repeater
 // returns Success. The repeater runs forever if n == 0.
 func RepeatUntil(params RepeatUntilParams, child core.Node) core.Node {
@@ -31,7 +39,7 @@ type repeatUntil struct {
 
 func (d *repeatUntil) repeat(ctx context.Context, enqueue core.EnqueueFn) error {
 	internal.Logger.DebugContext(ctx, "Repeating", "name", d.Name())
-	return enqueue(core.TargetNodeEvent(d.Id()))
+	return enqueue(core.ResumeEvent(d.Id()))
 }
 
 func (d *repeatUntil) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
@@ -0,0 +1,92 @@
+package decorator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/jbcpollak/greenstalk/v2/core"
+	"github.com/jbcpollak/greenstalk/v2/internal"
+)
+
+type RetryParams struct {
+	core.BaseParams
+
+	// Attempts is the number of times the child may be tried. A negative
+	// value retries forever.
+	Attempts int
+}
+
+// Retry updates its child until it succeeds, at which point it returns
+// Success, or until it has failed params.Attempts times, at which point it
+// fails.
+func Retry(params RetryParams, child core.Node) core.Node {
+	base := core.NewDecorator(params, child)
+	return &retry{Decorator: base}
+}
+
+type retry struct {
+	core.Decorator[RetryParams]
+	failed int
+}
+
+func (d *retry) again(ctx context.Context, enqueue core.EnqueueFn) error {
+	internal.Logger.DebugContext(ctx, "Retrying", "name", d.Name(), "failed", d.failed)
+	return enqueue(core.ResumeEvent(d.Id()))
+}
+
+func (d *retry) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
+	d.failed = 0
+	if d.Params.Attempts == 0 {
+		return core.FailureResultWithReason("no attempts allowed")
+	}
+	return d.Tick(ctx, evt)
+}
+
+func (d *retry) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
+	result := core.Update(ctx, d.Child, evt)
+	if result.Status() != core.StatusFailure {
+		return result
+	}
+
+	d.failed++
+	if d.Params.Attempts > 0 && d.failed >= d.Params.Attempts {
+		return core.FailureCausedBy(fmt.Sprintf("child %s failed %d times", d.Child.Name(), d.failed), d.Child)
+	}
+	return core.InitRunningResult(d.again)
+}
+
+func (d *retry) Leave(context.Context) error {
+	return nil
+}
+
+type retryState struct {
+	Failed int `json:"failed"`
+}
+
+// SaveState saves how often the child has failed so far.
+func (d *retry) SaveState() (json.RawMessage, error) {
+	return json.Marshal(retryState{Failed: d.failed})
+}
+
+// RestoreState restores the count saved by SaveState.
+func (d *retry) RestoreState(raw json.RawMessage) error {
+	var state retryState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return err
+	}
+	d.failed = state.Failed
+	return nil
+}
+
+// StandardNode implements core.Standard.
+func (d *retry) StandardNode() (string, []core.Port) {
+	return "RetryUntilSuccessful", []core.Port{{Name: "num_attempts", Value: strconv.Itoa(d.Params.Attempts)}}
+}
+
+var (
+	_ core.Node     = (*retry)(nil)
+	_ core.Stateful = (*retry)(nil)
+	_ core.Standard = (*retry)(nil)
+)
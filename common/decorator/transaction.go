@@ -0,0 +1,115 @@
+package decorator
+
+import (
+	"context"
+
+	"github.com/jbcpollak/greenstalk/v2/blackboard"
+	"github.com/jbcpollak/greenstalk/v2/common/state"
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+type TransactionParams struct {
+	core.BaseParams
+
+	// State lists the state providers rolled back along with the
+	// blackboard. Their writes are not isolated: other branches see them
+	// until they are rolled back, and they are not checked for conflicts.
+	State []state.Snapshotter
+}
+
+// TransactionNamed runs its child on a transaction of the tree's
+// blackboard, see blackboard.Blackboard.Begin. The child's writes are
+// committed when it succeeds, and discarded when it fails, errors or is
+// halted, including those made by the RunningFns of its descendants. If
+// the blackboard entries it wrote were changed meanwhile, such as by
+// another branch of a Parallel, nothing is committed and the Transaction
+// fails with a *blackboard.ConflictError.
+//
+// Wrapping each branch of a Parallel in a Transaction gives the branches
+// isolated views of the blackboard. Without a blackboard, the child runs
+// as is. State providers are only rolled back if they are passed to
+// TransactionWithState.
+func TransactionNamed(name string, child core.Node) core.Node {
+	return TransactionWithState(TransactionParams{BaseParams: core.BaseParams(name)}, child)
+}
+
+func Transaction(child core.Node) core.Node {
+	return TransactionNamed("Transaction", child)
+}
+
+// TransactionWithState is a Transaction that also restores the values
+// params.State had when it was activated, unless its child succeeds and
+// its blackboard writes are committed.
+func TransactionWithState(params TransactionParams, child core.Node) core.Node {
+	base := core.NewDecorator(params, child)
+	return &transaction{Decorator: base}
+}
+
+type transaction struct {
+	core.Decorator[TransactionParams]
+
+	// tx is the transaction of the current run.
+	tx *blackboard.Blackboard
+	// restore restores the state of the current run.
+	restore []func()
+}
+
+func (d *transaction) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
+	d.tx = nil
+	d.restore = nil
+	for _, s := range d.Params.State {
+		d.restore = append(d.restore, s.Snapshot())
+	}
+	return d.Tick(ctx, evt)
+}
+
+func (d *transaction) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
+	if parent, ok := blackboard.FromContext(ctx); ok {
+		// A restored node is ticked without being activated.
+		if d.tx == nil || d.tx.Parent() != parent {
+			d.tx = parent.Begin()
+		}
+		ctx = blackboard.WithContext(ctx, d.tx)
+	}
+
+	result := core.Update(ctx, d.Child, evt)
+	switch result.Status() {
+	case core.StatusSuccess:
+		if err := d.commit(); err != nil {
+			return core.FailureResultWithError(err)
+		}
+	case core.StatusFailure, core.StatusError:
+		d.rollback()
+	}
+	return result
+}
+
+func (d *transaction) commit() error {
+	if d.tx != nil {
+		if err := d.tx.Commit(); err != nil {
+			d.rollback()
+			return err
+		}
+	}
+	d.restore = nil
+	return nil
+}
+
+func (d *transaction) rollback() {
+	if d.tx != nil {
+		d.tx.Rollback()
+	}
+	for _, restore := range d.restore {
+		restore()
+	}
+	d.restore = nil
+}
+
+// Leave discards the writes of a halted child.
+func (d *transaction) Leave(context.Context) error {
+	d.rollback()
+	d.tx = nil
+	return nil
+}
+
+var _ core.Node = (*transaction)(nil)
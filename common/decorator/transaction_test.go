@@ -0,0 +1,168 @@
+package decorator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jbcpollak/greenstalk/v2"
+	"github.com/jbcpollak/greenstalk/v2/blackboard"
+	"github.com/jbcpollak/greenstalk/v2/common/action"
+	"github.com/jbcpollak/greenstalk/v2/common/composite"
+	"github.com/jbcpollak/greenstalk/v2/common/state"
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+var ammoKey = blackboard.NewKey[int]("ammo")
+
+// spend decrements the ammo on the blackboard, and is Running once
+// before succeeding if slow.
+type spend struct {
+	core.Leaf[core.BaseParams]
+	slow bool
+}
+
+func (a *spend) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
+	b, _ := blackboard.FromContext(ctx)
+	blackboard.Set(b, ammoKey, blackboard.GetOr(b, ammoKey, 0)-1)
+	if a.slow {
+		return core.RunningResult()
+	}
+	return core.SuccessResult()
+}
+
+func (a *spend) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
+	return core.SuccessResult()
+}
+
+func (a *spend) Leave(context.Context) error {
+	return nil
+}
+
+func newSpend(slow bool) core.Node {
+	return &spend{Leaf: core.NewLeaf(core.BaseParams("Spend")), slow: slow}
+}
+
+func TestTransactionRollback(t *testing.T) {
+	b := blackboard.New()
+	blackboard.Set(b, ammoKey, 3)
+	tree, err := greenstalk.NewBehaviorTree(
+		Transaction(composite.Sequence(newSpend(false), action.Fail(action.FailParams{}))),
+		greenstalk.WithBlackboard(b),
+	)
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	if result := tree.Update(t.Context(), core.DefaultEvent{}); result.Status() != core.StatusFailure {
+		t.Errorf("Expected failure, got %v", result)
+	}
+	if ammo, _ := blackboard.Get(b, ammoKey); ammo != 3 {
+		t.Errorf("Expected the write to be rolled back, got %d", ammo)
+	}
+}
+
+func TestTransactionAsyncRollback(t *testing.T) {
+	spendAsync := action.AsyncFunctionAction(action.AsyncFunctionActionParams{
+		BaseParams: "SpendAsync",
+		Func: func(ctx context.Context) core.ResultDetails {
+			b, _ := blackboard.FromContext(ctx)
+			blackboard.Set(b, ammoKey, blackboard.GetOr(b, ammoKey, 0)-1)
+			return core.SuccessResult()
+		},
+	})
+
+	b := blackboard.New()
+	blackboard.Set(b, ammoKey, 3)
+	tree, err := greenstalk.NewBehaviorTree(
+		Transaction(composite.Sequence(spendAsync, action.Fail(action.FailParams{}))),
+		greenstalk.WithBlackboard(b),
+	)
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	result, err := tree.RunToCompletion(t.Context(), core.DefaultEvent{})
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if result.Status() != core.StatusFailure {
+		t.Errorf("Expected failure, got %v", result)
+	}
+	if ammo, _ := blackboard.Get(b, ammoKey); ammo != 3 {
+		t.Errorf("Expected the async write to be rolled back, got %d", ammo)
+	}
+}
+
+func TestTransactionState(t *testing.T) {
+	ammo := &state.StateProvider[int]{}
+	ammo.Set(3)
+	spendState := func(then core.Node) core.Node {
+		return TransactionWithState(
+			TransactionParams{BaseParams: "Transaction", State: []state.Snapshotter{ammo}},
+			composite.Sequence(
+				action.FunctionAction(action.FunctionActionParams{
+					Func: func() core.ResultDetails {
+						ammo.Set(ammo.Get() - 1)
+						return core.SuccessResult()
+					},
+				}),
+				then,
+			),
+		)
+	}
+
+	tree, err := greenstalk.NewBehaviorTree(spendState(action.Fail(action.FailParams{})))
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if result := tree.Update(t.Context(), core.DefaultEvent{}); result.Status() != core.StatusFailure {
+		t.Errorf("Expected failure, got %v", result)
+	}
+	if got := ammo.Get(); got != 3 {
+		t.Errorf("Expected the write to be rolled back, got %d", got)
+	}
+
+	tree, err = greenstalk.NewBehaviorTree(spendState(action.Succeed(action.SucceedParams{})))
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if result := tree.Update(t.Context(), core.DefaultEvent{}); result.Status() != core.StatusSuccess {
+		t.Errorf("Expected success, got %v", result)
+	}
+	if got := ammo.Get(); got != 2 {
+		t.Errorf("Expected the write to be kept, got %d", got)
+	}
+}
+
+func TestTransactionConflict(t *testing.T) {
+	b := blackboard.New()
+	blackboard.Set(b, ammoKey, 3)
+	slow := Transaction(newSpend(true))
+	tree, err := greenstalk.NewBehaviorTree(
+		composite.Parallel(2, 1, slow, Transaction(newSpend(false))),
+		greenstalk.WithBlackboard(b),
+	)
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	if result := tree.Update(t.Context(), core.DefaultEvent{}); result.Status() != core.StatusRunning {
+		t.Fatalf("Expected the slow branch to run, got %v", result)
+	}
+	if ammo, _ := blackboard.Get(b, ammoKey); ammo != 2 {
+		t.Errorf("Expected the fast branch to commit, got %d", ammo)
+	}
+
+	if result := tree.Update(t.Context(), core.DefaultEvent{}); result.Status() != core.StatusFailure {
+		t.Errorf("Expected the conflict to fail the tree, got %v", result)
+	}
+	var conflict *blackboard.ConflictError
+	failure, _ := slow.Result().(core.FailureResultDetails)
+	if !errors.As(failure.Err, &conflict) {
+		t.Errorf("Expected the slow branch to fail with a conflict, got %v", slow.Result())
+	}
+	if ammo, _ := blackboard.Get(b, ammoKey); ammo != 2 {
+		t.Errorf("Expected the conflicting write to be discarded, got %d", ammo)
+	}
+}
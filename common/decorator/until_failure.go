@@ -6,16 +6,24 @@ import (
 
 // UntilFailure updates its child until it returns Failure.
 func UntilFailureNamed(name string, child core.Node) core.Node {
-	untilFailure := func(result core.ResultDetails) bool {
+	until := func(result core.ResultDetails) bool {
 		return result.Status() == core.StatusFailure
 	}
 
-	return RepeatUntil(RepeatUntilParams{
+	base := core.NewDecorator(RepeatUntilParams{
 		BaseParams: core.BaseParams(name),
-		Until:      untilFailure,
+		Until:      until,
 	}, child)
+	return &untilFailure{repeatUntil{Decorator: base}}
 }
 
 func UntilFailure(child core.Node) core.Node {
 	return UntilFailureNamed("UntilFailure", child)
 }
+
+// untilFailure is a RepeatUntil exported as UntilFailure.
+type untilFailure struct {
+	repeatUntil
+}
+
+var _ core.Node = (*untilFailure)(nil)
@@ -6,16 +6,24 @@ import (
 
 // UntilSuccess updates its child until it returns Success.
 func UntilSuccessNamed(name string, child core.Node) core.Node {
-	untilSuccess := func(result core.ResultDetails) bool {
+	until := func(result core.ResultDetails) bool {
 		return result.Status() == core.StatusSuccess
 	}
 
-	return RepeatUntil(RepeatUntilParams{
+	base := core.NewDecorator(RepeatUntilParams{
 		BaseParams: core.BaseParams(name),
-		Until:      untilSuccess,
+		Until:      until,
 	}, child)
+	return &untilSuccess{repeatUntil{Decorator: base}}
 }
 
 func UntilSuccess(child core.Node) core.Node {
 	return UntilSuccessNamed("UntilSuccess", child)
 }
+
+// untilSuccess is a RepeatUntil exported as UntilSuccess.
+type untilSuccess struct {
+	repeatUntil
+}
+
+var _ core.Node = (*untilSuccess)(nil)
@@ -2,6 +2,8 @@ package decorator
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 
 	"github.com/jbcpollak/greenstalk/v2/core"
 )
@@ -26,9 +28,13 @@ type with struct {
 	core.Decorator[core.BaseParams]
 	createCloseable func(context.Context) (func(context.Context) error, error)
 	closeFn         func(context.Context) error
+	// reopen is set when the node is restored from a checkpoint while
+	// Running, since the resource it had opened is gone.
+	reopen bool
 }
 
 func (d *with) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
+	d.closeFn = nil
 	closeable, err := d.createCloseable(ctx)
 	if err != nil {
 		return core.ErrorResult(err)
@@ -39,9 +45,47 @@ func (d *with) Activate(ctx context.Context, evt core.Event) core.ResultDetails
 }
 
 func (d *with) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
+	if d.reopen {
+		d.reopen = false
+		closeable, err := d.createCloseable(ctx)
+		if err != nil {
+			return core.ErrorResult(err)
+		}
+		d.closeFn = closeable
+	}
 	return core.Update(ctx, d.Child, evt)
 }
 
+// SaveState saves nothing: the resource can't be checkpointed.
+func (d *with) SaveState() (json.RawMessage, error) {
+	return nil, nil
+}
+
+// RestoreState arranges for the resource to be created again on the next
+// Tick if the node was Running.
+func (d *with) RestoreState(json.RawMessage) error {
+	d.reopen = d.Result().Status() == core.StatusRunning
+	return nil
+}
+
+// Validate checks that there is a function to create the closeable with.
+func (d *with) Validate(ctx context.Context) error {
+	var errs []error
+	if d.createCloseable == nil {
+		errs = append(errs, errors.New("createCloseable function is nil"))
+	}
+	errs = append(errs, d.Decorator.Validate(ctx))
+	return errors.Join(errs...)
+}
+
 func (d *with) Leave(ctx context.Context) error {
-	return d.closeFn(ctx)
+	// The resource may not exist: it failed to be created, or the node
+	// was restored and halted before it was created again.
+	d.reopen = false
+	closeFn := d.closeFn
+	d.closeFn = nil
+	if closeFn == nil {
+		return nil
+	}
+	return closeFn(ctx)
 }
@@ -2,6 +2,7 @@ package decorator
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
@@ -211,9 +212,10 @@ func TestWithInitError(t *testing.T) {
 	evt := core.DefaultEvent{}
 	wg.Go(func() {
 		err = tree.EventLoop(ctx, evt)
+		var nodeErr *core.NodeError
 		if err == nil {
 			t.Error("Should have errored here")
-		} else if err.Error() != "This is an error" {
+		} else if !errors.As(err, &nodeErr) || nodeErr.Err.Error() != "This is an error" {
 			t.Errorf("Error does not have correct contents: %v", err)
 		}
 	})
@@ -0,0 +1,144 @@
+package state
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ConcurrentStateProvider is a StateProvider that is safe for concurrent
+// use, such as by RunningFns and the event loop. Reads share a RWMutex.
+type ConcurrentStateProvider[T any] struct {
+	mu        sync.RWMutex
+	value     T
+	observers observers[T]
+}
+
+func (p *ConcurrentStateProvider[T]) Get() T {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.value
+}
+
+func (p *ConcurrentStateProvider[T]) Set(val T) {
+	p.Swap(val)
+}
+
+func (p *ConcurrentStateProvider[T]) Reset() {
+	var zeroValue T
+	p.Swap(zeroValue)
+}
+
+// Swap sets the value to val and returns the previous one.
+func (p *ConcurrentStateProvider[T]) Swap(val T) T {
+	p.mu.Lock()
+	old := p.value
+	p.value = val
+	p.mu.Unlock()
+
+	p.observers.notify(val)
+	return old
+}
+
+// Update sets the value to fn of the current one, atomically, and returns
+// the new value. fn must not call p.
+func (p *ConcurrentStateProvider[T]) Update(fn func(T) T) T {
+	p.mu.Lock()
+	val := fn(p.value)
+	p.value = val
+	p.mu.Unlock()
+
+	p.observers.notify(val)
+	return val
+}
+
+// Subscribe calls fn with the new value after every change, until the
+// returned function is called. Concurrent changes may be notified out of
+// order, subscribers needing the latest value should Get it.
+func (p *ConcurrentStateProvider[T]) Subscribe(fn func(T)) func() {
+	return p.observers.subscribe(fn)
+}
+
+// AtomicStateProvider is a StateProvider that is safe for concurrent use
+// and never blocks: the value is held by an atomic.Pointer, and writes
+// replace it.
+type AtomicStateProvider[T any] struct {
+	value     atomic.Pointer[T]
+	observers observers[T]
+}
+
+func (p *AtomicStateProvider[T]) Get() T {
+	if val := p.value.Load(); val != nil {
+		return *val
+	}
+	var zeroValue T
+	return zeroValue
+}
+
+func (p *AtomicStateProvider[T]) Set(val T) {
+	p.Swap(val)
+}
+
+func (p *AtomicStateProvider[T]) Reset() {
+	var zeroValue T
+	p.Swap(zeroValue)
+}
+
+// Swap sets the value to val and returns the previous one.
+func (p *AtomicStateProvider[T]) Swap(val T) T {
+	var old T
+	if prev := p.value.Swap(&val); prev != nil {
+		old = *prev
+	}
+	p.observers.notify(val)
+	return old
+}
+
+// Update sets the value to fn of the current one and returns the new
+// value. If another goroutine changes the value meanwhile, fn is called
+// again with the new one, so it must not have side effects.
+func (p *AtomicStateProvider[T]) Update(fn func(T) T) T {
+	for {
+		prev := p.value.Load()
+		var current T
+		if prev != nil {
+			current = *prev
+		}
+		val := fn(current)
+		if p.value.CompareAndSwap(prev, &val) {
+			p.observers.notify(val)
+			return val
+		}
+	}
+}
+
+// Subscribe calls fn with the new value after every change, until the
+// returned function is called. Concurrent changes may be notified out of
+// order, subscribers needing the latest value should Get it.
+func (p *AtomicStateProvider[T]) Subscribe(fn func(T)) func() {
+	return p.observers.subscribe(fn)
+}
+
+// CASStateProvider is an AtomicStateProvider of comparable values, which
+// can also be changed with CompareAndSwap.
+type CASStateProvider[T comparable] struct {
+	AtomicStateProvider[T]
+}
+
+// CompareAndSwap sets the value to new if it is old, and reports whether
+// it did.
+func (p *CASStateProvider[T]) CompareAndSwap(old, new T) bool {
+	for {
+		prev := p.value.Load()
+		var current T
+		if prev != nil {
+			current = *prev
+		}
+		if current != old {
+			return false
+		}
+		if p.value.CompareAndSwap(prev, &new) {
+			p.observers.notify(new)
+			return true
+		}
+	}
+}
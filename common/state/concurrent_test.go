@@ -0,0 +1,60 @@
+package state
+
+import (
+	"sync"
+	"testing"
+)
+
+type updater interface {
+	State[int]
+	Update(fn func(int) int) int
+	Swap(val int) int
+}
+
+func TestConcurrentUpdate(t *testing.T) {
+	providers := map[string]updater{
+		"ConcurrentStateProvider":   &ConcurrentStateProvider[int]{},
+		"AtomicStateProvider":       &AtomicStateProvider[int]{},
+		"CASStateProvider":          &CASStateProvider[int]{},
+		"SynchronizedStateProvider": &SynchronizedStateProvider[int]{},
+	}
+	for name, p := range providers {
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Go(func() {
+				for range 100 {
+					p.Update(func(v int) int { return v + 1 })
+					_ = p.Get()
+				}
+			})
+		}
+		wg.Wait()
+
+		if p.Get() != 800 {
+			t.Errorf("%s: expected 800, got %d", name, p.Get())
+		}
+		if old := p.Swap(3); old != 800 || p.Get() != 3 {
+			t.Errorf("%s: expected Swap to return 800 and set 3, got %d, %d", name, old, p.Get())
+		}
+		p.Reset()
+		if p.Get() != 0 {
+			t.Errorf("%s: expected Reset to zero the value, got %d", name, p.Get())
+		}
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	p := &CASStateProvider[string]{}
+	var notified []string
+	p.Subscribe(func(v string) { notified = append(notified, v) })
+
+	if !p.CompareAndSwap("", "a") {
+		t.Errorf("Expected the swap from the zero value to succeed")
+	}
+	if p.CompareAndSwap("b", "c") {
+		t.Errorf("Expected the swap from a stale value to fail")
+	}
+	if p.Get() != "a" || len(notified) != 1 || notified[0] != "a" {
+		t.Errorf("Expected a single change to a, got %q, notified %v", p.Get(), notified)
+	}
+}
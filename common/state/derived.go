@@ -0,0 +1,190 @@
+package state
+
+import (
+	"sync"
+	"time"
+)
+
+// Derived is implemented by the providers computed from other state,
+// such as those returned by Map and Combine2, so their inputs can be
+// inspected.
+type Derived interface {
+	Sources() []any
+}
+
+type derived[T any] struct {
+	get     func() T
+	sources []any
+}
+
+func (d *derived[T]) Get() T {
+	return d.get()
+}
+
+func (d *derived[T]) Sources() []any {
+	return d.sources
+}
+
+// Map returns a provider of fn applied to the value of src. fn is called
+// on every Get.
+func Map[T, U any](src StateGetter[T], fn func(T) U) StateGetter[U] {
+	return &derived[U]{
+		get:     func() U { return fn(src.Get()) },
+		sources: []any{src},
+	}
+}
+
+// Combine2 returns a provider of fn applied to the values of a and b.
+func Combine2[A, B, R any](a StateGetter[A], b StateGetter[B], fn func(A, B) R) StateGetter[R] {
+	return &derived[R]{
+		get:     func() R { return fn(a.Get(), b.Get()) },
+		sources: []any{a, b},
+	}
+}
+
+// Combine3 returns a provider of fn applied to the values of a, b and c.
+func Combine3[A, B, C, R any](a StateGetter[A], b StateGetter[B], c StateGetter[C], fn func(A, B, C) R) StateGetter[R] {
+	return &derived[R]{
+		get:     func() R { return fn(a.Get(), b.Get(), c.Get()) },
+		sources: []any{a, b, c},
+	}
+}
+
+// Filter returns a provider of the value of src when keep accepts it, and
+// of def otherwise.
+func Filter[T any](src StateGetter[T], keep func(T) bool, def T) StateGetter[T] {
+	return &derived[T]{
+		get: func() T {
+			if val := src.Get(); keep(val) {
+				return val
+			}
+			return def
+		},
+		sources: []any{src},
+	}
+}
+
+// HistoryProvider is a StateProvider that remembers the last values it
+// was set to. It is safe for concurrent use.
+type HistoryProvider[T any] struct {
+	mu        sync.Mutex
+	values    []T
+	size      int
+	observers observers[T]
+}
+
+// History returns a provider keeping the last n values it was set to,
+// including the current one.
+func History[T any](n int) *HistoryProvider[T] {
+	return &HistoryProvider[T]{size: max(n, 1)}
+}
+
+// Get returns the latest value, or the zero value if it was never set.
+func (p *HistoryProvider[T]) Get() T {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.values) == 0 {
+		var zeroValue T
+		return zeroValue
+	}
+	return p.values[len(p.values)-1]
+}
+
+func (p *HistoryProvider[T]) Set(val T) {
+	p.mu.Lock()
+	if len(p.values) == p.size {
+		p.values = append(p.values[:0], p.values[1:]...)
+	}
+	p.values = append(p.values, val)
+	p.mu.Unlock()
+
+	p.observers.notify(val)
+}
+
+// Reset forgets every value.
+func (p *HistoryProvider[T]) Reset() {
+	p.mu.Lock()
+	p.values = nil
+	p.mu.Unlock()
+
+	var zeroValue T
+	p.observers.notify(zeroValue)
+}
+
+// Values returns the remembered values, oldest first.
+func (p *HistoryProvider[T]) Values() []T {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]T(nil), p.values...)
+}
+
+// Previous returns the value before the latest one, if there is one.
+func (p *HistoryProvider[T]) Previous() (T, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.values) < 2 {
+		var zeroValue T
+		return zeroValue, false
+	}
+	return p.values[len(p.values)-2], true
+}
+
+// Subscribe calls fn with the new value after every Set and Reset, until
+// the returned function is called.
+func (p *HistoryProvider[T]) Subscribe(fn func(T)) func() {
+	return p.observers.subscribe(fn)
+}
+
+// TTLProvider is a StateProvider whose value reverts to the zero value a
+// set duration after it was set. It is safe for concurrent use.
+type TTLProvider[T any] struct {
+	mu    sync.Mutex
+	value T
+	set   time.Time
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+// TTL returns a provider whose values expire after ttl.
+func TTL[T any](ttl time.Duration) *TTLProvider[T] {
+	return &TTLProvider[T]{ttl: ttl, now: time.Now}
+}
+
+// Get returns the value, or the zero value if it has expired.
+func (p *TTLProvider[T]) Get() T {
+	val, _ := p.Lookup()
+	return val
+}
+
+// Lookup returns the value, and whether it was set and hasn't expired.
+func (p *TTLProvider[T]) Lookup() (T, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.set.IsZero() || p.now().Sub(p.set) >= p.ttl {
+		var zeroValue T
+		return zeroValue, false
+	}
+	return p.value, true
+}
+
+func (p *TTLProvider[T]) Set(val T) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.value = val
+	p.set = p.now()
+}
+
+func (p *TTLProvider[T]) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var zeroValue T
+	p.value = zeroValue
+	p.set = time.Time{}
+}
+
+var (
+	_ State[int]        = (*HistoryProvider[int])(nil)
+	_ Subscribable[int] = (*HistoryProvider[int])(nil)
+	_ State[int]        = (*TTLProvider[int])(nil)
+	_ Derived           = (*derived[int])(nil)
+)
@@ -0,0 +1,81 @@
+package state
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDerived(t *testing.T) {
+	x := &StateProvider[int]{}
+	y := &StateProvider[int]{}
+	sum := Combine2(x, y, func(a, b int) int { return a + b })
+	label := Map(sum, func(v int) string {
+		if v > 10 {
+			return "far"
+		}
+		return "near"
+	})
+	positive := Filter(sum, func(v int) bool { return v > 0 }, -1)
+	total := Combine3(x, y, MakeConstStateProvider(100), func(a, b, c int) int { return a + b + c })
+
+	x.Set(4)
+	y.Set(9)
+	if sum.Get() != 13 || label.Get() != "far" || positive.Get() != 13 || total.Get() != 113 {
+		t.Errorf("Unexpected values %d, %q, %d, %d", sum.Get(), label.Get(), positive.Get(), total.Get())
+	}
+
+	y.Set(-9)
+	if label.Get() != "near" || positive.Get() != -1 {
+		t.Errorf("Expected the derived values to follow their sources, got %q, %d", label.Get(), positive.Get())
+	}
+
+	if sources := label.(Derived).Sources(); len(sources) != 1 || sources[0] != sum {
+		t.Errorf("Expected the mapped provider to be inspectable, got %v", sources)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	h := History[int](3)
+	if _, ok := h.Previous(); ok {
+		t.Errorf("Expected no previous value")
+	}
+	for i := range 5 {
+		h.Set(i)
+	}
+	if values := h.Values(); !slices.Equal(values, []int{2, 3, 4}) {
+		t.Errorf("Expected the last 3 values, got %v", values)
+	}
+	if prev, ok := h.Previous(); !ok || prev != 3 || h.Get() != 4 {
+		t.Errorf("Expected 4 after 3, got %d after %d", h.Get(), prev)
+	}
+	h.Reset()
+	if len(h.Values()) != 0 || h.Get() != 0 {
+		t.Errorf("Expected Reset to forget the values")
+	}
+
+	h.Set(1)
+	restore := h.Snapshot()
+	h.Set(2)
+	h.Set(3)
+	restore()
+	if values := h.Values(); !slices.Equal(values, []int{1}) {
+		t.Errorf("Expected the snapshot to be restored, got %v", values)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	now := time.Now()
+	p := TTL[string](time.Second)
+	p.now = func() time.Time { return now }
+
+	p.Set("seen")
+	now = now.Add(999 * time.Millisecond)
+	if p.Get() != "seen" {
+		t.Errorf("Expected the value before it expires, got %q", p.Get())
+	}
+	now = now.Add(time.Millisecond)
+	if val, ok := p.Lookup(); ok || val != "" {
+		t.Errorf("Expected the value to expire, got %q", val)
+	}
+}
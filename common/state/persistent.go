@@ -0,0 +1,328 @@
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jbcpollak/greenstalk/v2/internal"
+)
+
+// Codec encodes the values of a PersistentStateProvider.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec encodes values with encoding/json.
+var JSONCodec Codec = jsonCodec{}
+
+// GobCodec encodes values with encoding/gob.
+var GobCodec Codec = gobCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.MarshalIndent(v, "", "  ")
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func (gobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// Migration converts the encoded value of a file written at schema
+// version from to the current schema version.
+type Migration func(from int, data []byte) ([]byte, error)
+
+// PersistentOption is used to configure a PersistentStateProvider.
+type PersistentOption func(*persistentConfig)
+
+type persistentConfig struct {
+	codec   Codec
+	batch   time.Duration
+	version int
+	migrate Migration
+}
+
+// WithCodec sets how values are encoded, JSONCodec by default.
+func WithCodec(codec Codec) PersistentOption {
+	return func(c *persistentConfig) {
+		c.codec = codec
+	}
+}
+
+// WithBatching writes the value at most once every interval, rather than
+// on every change. Flush and Close write pending changes straight away.
+func WithBatching(interval time.Duration) PersistentOption {
+	return func(c *persistentConfig) {
+		c.batch = interval
+	}
+}
+
+// WithSchemaVersion sets the schema version of the value, 0 by default.
+// Files written at an older version are converted by migrate when they
+// are loaded, and rewritten at version.
+func WithSchemaVersion(version int, migrate Migration) PersistentOption {
+	return func(c *persistentConfig) {
+		c.version = version
+		c.migrate = migrate
+	}
+}
+
+// PersistentStateProvider is a StateProvider whose value is kept in a
+// file, so it outlives the process. It is safe for concurrent use.
+//
+// The file holds a "version N" line with the schema version, followed by
+// the encoded value. Files without that line are read as version 0, so
+// values can be written by hand. Writes go to a temporary file, which is
+// synced and renamed over the file, so the file is never left half
+// written.
+//
+// Since Set and Reset can't return errors, failed writes are logged and
+// reported by Err, Flush and Close.
+type PersistentStateProvider[T any] struct {
+	path   string
+	config persistentConfig
+
+	mu        sync.Mutex
+	value     T
+	dirty     bool
+	timer     *time.Timer
+	err       error
+	observers observers[T]
+
+	// writing serializes writes to the file.
+	writing sync.Mutex
+}
+
+// NewPersistentStateProvider returns a provider keeping its value in the
+// file at path, loading the value already there if the file exists. The
+// file's directory is created when the value is first written.
+func NewPersistentStateProvider[T any](path string, opts ...PersistentOption) (*PersistentStateProvider[T], error) {
+	p := &PersistentStateProvider[T]{
+		path:   path,
+		config: persistentConfig{codec: JSONCodec},
+	}
+	for _, opt := range opts {
+		opt(&p.config)
+	}
+
+	migrated, err := p.load()
+	if err != nil {
+		return nil, fmt.Errorf("loading %s: %w", path, err)
+	}
+	if migrated {
+		if err := p.write(); err != nil {
+			return nil, fmt.Errorf("rewriting %s: %w", path, err)
+		}
+	}
+	return p, nil
+}
+
+// load reads the file, and reports whether it had to be migrated.
+func (p *PersistentStateProvider[T]) load() (bool, error) {
+	data, err := os.ReadFile(p.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	version := 0
+	if line, rest, ok := bytes.Cut(data, []byte("\n")); ok {
+		if _, err := fmt.Sscanf(string(line), "version %d", &version); err == nil {
+			data = rest
+		}
+	}
+
+	migrated := false
+	switch {
+	case version > p.config.version:
+		return false, fmt.Errorf("schema version %d is newer than %d", version, p.config.version)
+	case version < p.config.version:
+		if p.config.migrate == nil {
+			return false, fmt.Errorf("no migration from schema version %d", version)
+		}
+		if data, err = p.config.migrate(version, data); err != nil {
+			return false, fmt.Errorf("migrating from schema version %d: %w", version, err)
+		}
+		migrated = true
+	}
+
+	return migrated, p.config.codec.Unmarshal(data, &p.value)
+}
+
+func (p *PersistentStateProvider[T]) Get() T {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.value
+}
+
+func (p *PersistentStateProvider[T]) Set(val T) {
+	p.mu.Lock()
+	p.value = val
+	p.mu.Unlock()
+
+	p.changed(val)
+}
+
+// Reset sets the value to the zero value, and persists it.
+func (p *PersistentStateProvider[T]) Reset() {
+	var zeroValue T
+	p.Set(zeroValue)
+}
+
+// Subscribe calls fn with the new value after every Set and Reset, until
+// the returned function is called.
+func (p *PersistentStateProvider[T]) Subscribe(fn func(T)) func() {
+	return p.observers.subscribe(fn)
+}
+
+func (p *PersistentStateProvider[T]) changed(val T) {
+	p.observers.notify(val)
+
+	p.mu.Lock()
+	p.dirty = true
+	batch := p.config.batch
+	if batch > 0 && p.timer == nil {
+		p.timer = time.AfterFunc(batch, func() { p.Flush() })
+	}
+	p.mu.Unlock()
+
+	if batch <= 0 {
+		p.Flush()
+	}
+}
+
+func (p *PersistentStateProvider[T]) record(err error) {
+	if err != nil {
+		internal.Logger.Error("Could not persist state", "path", p.path, "err", err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+// Err returns the error of the last write, if it failed.
+func (p *PersistentStateProvider[T]) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Flush writes the value if it has changes that haven't been written.
+func (p *PersistentStateProvider[T]) Flush() error {
+	p.mu.Lock()
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	if !p.dirty {
+		err := p.err
+		p.mu.Unlock()
+		return err
+	}
+	p.mu.Unlock()
+
+	err := p.write()
+	p.record(err)
+	if err != nil {
+		// Try again on the next Flush.
+		p.mu.Lock()
+		p.dirty = true
+		p.mu.Unlock()
+	}
+	return err
+}
+
+// Close writes pending changes. The provider can still be used, but
+// changes are written straight away from then on.
+func (p *PersistentStateProvider[T]) Close() error {
+	p.mu.Lock()
+	p.config.batch = 0
+	p.mu.Unlock()
+	return p.Flush()
+}
+
+// write writes the current value. Concurrent changes are written in
+// order, since the value is read once the previous write is done.
+func (p *PersistentStateProvider[T]) write() (err error) {
+	p.writing.Lock()
+	defer p.writing.Unlock()
+
+	p.mu.Lock()
+	val := p.value
+	p.dirty = false
+	p.mu.Unlock()
+
+	data, err := p.config.codec.Marshal(val)
+	if err != nil {
+		return fmt.Errorf("encoding: %w", err)
+	}
+
+	dir := filepath.Dir(p.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(p.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(tmp)
+	fmt.Fprintf(w, "version %d\n", p.config.version)
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), p.path); err != nil {
+		return err
+	}
+
+	// Make the rename itself durable, where directories can be synced.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+var (
+	_ State[int]        = (*PersistentStateProvider[int])(nil)
+	_ Subscribable[int] = (*PersistentStateProvider[int])(nil)
+)
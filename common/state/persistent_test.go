@@ -0,0 +1,93 @@
+package state
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type counts struct {
+	Runs  int
+	Names []string
+}
+
+func TestPersistentStateProvider(t *testing.T) {
+	for name, codec := range map[string]Codec{"json": JSONCodec, "gob": GobCodec} {
+		path := filepath.Join(t.TempDir(), "state", "counts")
+
+		p, err := NewPersistentStateProvider[counts](path, WithCodec(codec))
+		if err != nil {
+			t.Fatalf("%s: unexpectedly got %v", name, err)
+		}
+		p.Set(counts{Runs: 2, Names: []string{"a"}})
+		if err := p.Err(); err != nil {
+			t.Fatalf("%s: unexpectedly got %v", name, err)
+		}
+
+		reopened, err := NewPersistentStateProvider[counts](path, WithCodec(codec))
+		if err != nil {
+			t.Fatalf("%s: unexpectedly got %v", name, err)
+		}
+		if got := reopened.Get(); got.Runs != 2 || len(got.Names) != 1 {
+			t.Errorf("%s: expected the value to be loaded, got %v", name, got)
+		}
+
+		entries, _ := os.ReadDir(filepath.Dir(path))
+		if len(entries) != 1 {
+			t.Errorf("%s: expected no temporary files left, got %v", name, entries)
+		}
+	}
+}
+
+func TestPersistentBatching(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs")
+	p, err := NewPersistentStateProvider[int](path, WithBatching(time.Hour))
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	for i := range 10 {
+		p.Set(i)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the writes to be batched, got %v", err)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "version 0\n9" {
+		t.Errorf("Expected the last value to be written, got %q, %v", data, err)
+	}
+}
+
+func TestPersistentMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counts")
+	if err := os.WriteFile(path, []byte(`3`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Version 1 turned the bare number of runs into a counts struct.
+	migrate := func(from int, data []byte) ([]byte, error) {
+		return append(append([]byte(`{"Runs":`), data...), '}'), nil
+	}
+	p, err := NewPersistentStateProvider[counts](path, WithSchemaVersion(1, migrate))
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if p.Get().Runs != 3 {
+		t.Errorf("Expected the value to be migrated, got %v", p.Get())
+	}
+	if data, _ := os.ReadFile(path); !bytes.HasPrefix(data, []byte("version 1\n")) {
+		t.Errorf("Expected the file to be rewritten at version 1, got %q", data)
+	}
+
+	_, err = NewPersistentStateProvider[counts](path)
+	if err == nil || !strings.Contains(err.Error(), "schema version 1 is newer than 0") {
+		t.Errorf("Expected a newer file to be rejected, got %v", err)
+	}
+}
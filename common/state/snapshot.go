@@ -0,0 +1,68 @@
+package state
+
+// Snapshotter is implemented by state that can be rolled back, such as by
+// decorator.TransactionWithState.
+type Snapshotter interface {
+	// Snapshot returns a function restoring the current value.
+	Snapshot() (restore func())
+}
+
+// Restorable returns a Snapshotter of p, which restores its value with
+// Set. It is meant for providers that don't implement Snapshotter
+// themselves, such as blackboard bindings.
+func Restorable[T any](p State[T]) Snapshotter {
+	return restorable[T]{p}
+}
+
+type restorable[T any] struct {
+	p State[T]
+}
+
+func (r restorable[T]) Snapshot() func() {
+	val := r.p.Get()
+	return func() {
+		r.p.Set(val)
+	}
+}
+
+func (p *StateProvider[T]) Snapshot() func() {
+	val := p.value
+	return func() {
+		p.Set(val)
+	}
+}
+
+func (p *ConcurrentStateProvider[T]) Snapshot() func() {
+	val := p.Get()
+	return func() {
+		p.Set(val)
+	}
+}
+
+func (p *AtomicStateProvider[T]) Snapshot() func() {
+	val := p.Get()
+	return func() {
+		p.Set(val)
+	}
+}
+
+// Snapshot returns a function restoring the remembered values, notifying
+// subscribers of the latest one.
+func (p *HistoryProvider[T]) Snapshot() func() {
+	values := p.Values()
+	return func() {
+		p.mu.Lock()
+		p.values = values
+		p.mu.Unlock()
+
+		p.observers.notify(p.Get())
+	}
+}
+
+var (
+	_ Snapshotter = (*StateProvider[int])(nil)
+	_ Snapshotter = (*ConcurrentStateProvider[int])(nil)
+	_ Snapshotter = (*AtomicStateProvider[int])(nil)
+	_ Snapshotter = (*CASStateProvider[int])(nil)
+	_ Snapshotter = (*HistoryProvider[int])(nil)
+)
@@ -22,9 +22,15 @@ type State[T any] interface {
 }
 
 // State Providers allow tree nodes to share data between each other by either writing
-// the state value or reading from it.
+// the state value or reading from it. Set and Reset notify the provider's
+// subscribers, see Subscribe.
+//
+// A StateProvider is not safe for concurrent use. Values shared with
+// RunningFns or other goroutines should use a ConcurrentStateProvider,
+// AtomicStateProvider or CASStateProvider instead.
 type StateProvider[T any] struct {
-	value T
+	value     T
+	observers observers[T]
 }
 
 func (p *StateProvider[T]) Get() T {
@@ -33,11 +39,19 @@ func (p *StateProvider[T]) Get() T {
 
 func (p *StateProvider[T]) Set(val T) {
 	p.value = val
+	p.observers.notify(val)
 }
 
 func (p *StateProvider[T]) Reset() {
 	var zeroValue T
 	p.value = zeroValue
+	p.observers.notify(zeroValue)
+}
+
+// Subscribe calls fn with the new value after every Set and Reset, until
+// the returned function is called.
+func (p *StateProvider[T]) Subscribe(fn func(T)) func() {
+	return p.observers.subscribe(fn)
 }
 
 // Creates a state provider of a constant value that never changes
@@ -53,12 +67,67 @@ func (p *constStateProvider[T]) Get() T {
 	return p.value
 }
 
+// SynchronizedStateProvider is a StateProvider that is safe for concurrent
+// use, and embeds a Mutex which callers can hold around several calls. Its
+// methods don't use the Mutex, so it is safe to call them while holding
+// it, but Update is the simpler way to read and modify the value. Calls
+// made directly on the embedded StateProvider are not synchronized.
 type SynchronizedStateProvider[T any] struct {
 	sync.Mutex
 	StateProvider[T]
+
+	// mu guards the value of StateProvider for the methods below.
+	mu sync.RWMutex
+}
+
+func (p *SynchronizedStateProvider[T]) Get() T {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.value
+}
+
+func (p *SynchronizedStateProvider[T]) Set(val T) {
+	p.Swap(val)
+}
+
+func (p *SynchronizedStateProvider[T]) Reset() {
+	var zeroValue T
+	p.Swap(zeroValue)
+}
+
+// Swap sets the value to val and returns the previous one.
+func (p *SynchronizedStateProvider[T]) Swap(val T) T {
+	p.mu.Lock()
+	old := p.value
+	p.value = val
+	p.mu.Unlock()
+
+	p.observers.notify(val)
+	return old
+}
+
+// Update sets the value to fn of the current one, atomically, and returns
+// the new value. fn must not call p.
+func (p *SynchronizedStateProvider[T]) Update(fn func(T) T) T {
+	p.mu.Lock()
+	val := fn(p.value)
+	p.value = val
+	p.mu.Unlock()
+
+	p.observers.notify(val)
+	return val
 }
 
 var (
 	_ State[int] = (*StateProvider[int])(nil)
 	_ State[int] = (*SynchronizedStateProvider[int])(nil)
+
+	_ State[int] = (*ConcurrentStateProvider[int])(nil)
+	_ State[int] = (*AtomicStateProvider[int])(nil)
+	_ State[int] = (*CASStateProvider[int])(nil)
+
+	_ Subscribable[int] = (*StateProvider[int])(nil)
+	_ Subscribable[int] = (*SynchronizedStateProvider[int])(nil)
+	_ Subscribable[int] = (*ConcurrentStateProvider[int])(nil)
+	_ Subscribable[int] = (*AtomicStateProvider[int])(nil)
 )
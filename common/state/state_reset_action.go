@@ -1,19 +1,39 @@
 package state
 
 import (
-	"github.com/jbcpollak/greenstalk/v2/common/action"
+	"context"
+	"fmt"
+
 	"github.com/jbcpollak/greenstalk/v2/core"
 )
 
 // This node resets all provided states and returns SuccessStatus
 func MakeStateResetAction(states ...StateResetter) core.Node {
-	return action.FunctionAction(action.FunctionActionParams{
-		BaseParams: "stateReset",
-		Func: func() core.ResultDetails {
-			for _, state := range states {
-				state.Reset()
-			}
-			return core.SuccessResult()
-		},
-	})
+	base := core.NewLeaf(core.BaseParams("stateReset"))
+	return &stateReset{Leaf: base, states: states}
+}
+
+type stateReset struct {
+	core.Leaf[core.BaseParams]
+	states []StateResetter
+}
+
+func (a *stateReset) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
+	for _, state := range a.states {
+		state.Reset()
+	}
+	return core.SuccessResult()
 }
+
+func (a *stateReset) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
+	// Should never get here
+	return core.ErrorResult(
+		fmt.Errorf("StateReset node should not be ticked"),
+	)
+}
+
+func (a *stateReset) Leave(context.Context) error {
+	return nil
+}
+
+var _ core.Node = (*stateReset)(nil)
@@ -0,0 +1,86 @@
+package state
+
+import (
+	"context"
+	"sync"
+)
+
+// Subscribable is implemented by state that notifies subscribers when its
+// value changes.
+type Subscribable[T any] interface {
+	StateGetter[T]
+	// Subscribe calls fn with the new value after every change, until the
+	// returned function is called. fn runs on the goroutine making the
+	// change, so it must not block.
+	Subscribe(fn func(T)) (unsubscribe func())
+}
+
+// observers holds the subscribers of a provider. The zero value has none.
+type observers[T any] struct {
+	mu   sync.Mutex
+	next int
+	fns  map[int]func(T)
+}
+
+func (o *observers[T]) subscribe(fn func(T)) func() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.fns == nil {
+		o.fns = map[int]func(T){}
+	}
+	id := o.next
+	o.next++
+	o.fns[id] = fn
+
+	return func() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		delete(o.fns, id)
+	}
+}
+
+func (o *observers[T]) notify(val T) {
+	o.mu.Lock()
+	fns := make([]func(T), 0, len(o.fns))
+	for _, fn := range o.fns {
+		fns = append(fns, fn)
+	}
+	o.mu.Unlock()
+
+	// Subscribers may unsubscribe, or change the state, from fn.
+	for _, fn := range fns {
+		fn(val)
+	}
+}
+
+// Watch returns a channel receiving the values of s as it changes, until
+// ctx is done, when the channel is closed. A reader that falls behind
+// only gets the latest value.
+func Watch[T any](ctx context.Context, s Subscribable[T]) <-chan T {
+	ch := make(chan T, 1)
+	var mu sync.Mutex
+	closed := false
+
+	unsubscribe := s.Subscribe(func(val T) {
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		// Replace the value the reader hasn't picked up yet.
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- val
+	})
+
+	context.AfterFunc(ctx, func() {
+		unsubscribe()
+		mu.Lock()
+		defer mu.Unlock()
+		closed = true
+		close(ch)
+	})
+	return ch
+}
@@ -0,0 +1,3 @@
+// Package subtree provides the SubTree node, which shares a branch between
+// trees by building it from a named template in a Library.
+package subtree
@@ -0,0 +1,63 @@
+package subtree
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+// Template builds the root of a subtree from its ports.
+type Template func(ports Ports) (core.Node, error)
+
+type definition struct {
+	template Template
+	defaults map[string]any
+}
+
+// Library holds named templates. Templates are looked up when a SubTree
+// is activated, not when it is constructed, so they may be defined in any
+// order and may include SubTrees of themselves. A Library is safe for
+// concurrent use.
+type Library struct {
+	mu          sync.RWMutex
+	definitions map[string]definition
+}
+
+// NewLibrary returns an empty library.
+func NewLibrary() *Library {
+	return &Library{definitions: map[string]definition{}}
+}
+
+// Define adds a template under name, with the default values of its
+// parameters, which SubTreeParams.Params override. It fails if name is
+// already defined.
+func (l *Library) Define(name string, template Template, defaults map[string]any) error {
+	if template == nil {
+		return fmt.Errorf("template %q is nil", name)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.definitions[name]; ok {
+		return fmt.Errorf("template %q is already defined", name)
+	}
+	l.definitions[name] = definition{template: template, defaults: maps.Clone(defaults)}
+	return nil
+}
+
+// Defined reports whether a template called name has been defined.
+func (l *Library) Defined(name string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.definitions[name]
+	return ok
+}
+
+func (l *Library) lookup(name string) (definition, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	def, ok := l.definitions[name]
+	return def, ok
+}
@@ -0,0 +1,75 @@
+package subtree
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jbcpollak/greenstalk/v2/common/state"
+)
+
+// Ports are what a template builds a subtree from: its parameters, and
+// the state it reads from and writes to, remapped from the parent tree.
+type Ports struct {
+	params map[string]any
+	state  map[string]any
+}
+
+// Param returns the parameter called name, converting numbers to T so
+// that parameters decoded from JSON can be used as integers.
+func Param[T any](p Ports, name string) (T, error) {
+	var zero T
+	value, ok := p.params[name]
+	if !ok {
+		return zero, fmt.Errorf("unknown parameter %q", name)
+	}
+	if t, ok := value.(T); ok {
+		return t, nil
+	}
+
+	v, target := reflect.ValueOf(value), reflect.TypeFor[T]()
+	if v.IsValid() && isNumber(v.Type()) && isNumber(target) {
+		return v.Convert(target).Interface().(T), nil
+	}
+	return zero, fmt.Errorf("parameter %q is a %T, expected %s", name, value, target)
+}
+
+func isNumber(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// Input returns the state remapped to the input called name. Inputs must
+// be remapped by the SubTree.
+func Input[T any](p Ports, name string) (state.StateGetter[T], error) {
+	value, ok := p.state[name]
+	if !ok {
+		return nil, fmt.Errorf("input %q is not remapped", name)
+	}
+	getter, ok := value.(state.StateGetter[T])
+	if !ok {
+		return nil, fmt.Errorf("input %q is a %T, expected a StateGetter[%s]", name, value, reflect.TypeFor[T]())
+	}
+	return getter, nil
+}
+
+// Output returns the state remapped to the output called name. Outputs
+// that are not remapped get a provider private to this instance of the
+// subtree, shared by every lookup of name.
+func Output[T any](p Ports, name string) (state.StateSetter[T], error) {
+	value, ok := p.state[name]
+	if !ok {
+		provider := &state.StateProvider[T]{}
+		p.state[name] = provider
+		return provider, nil
+	}
+	setter, ok := value.(state.StateSetter[T])
+	if !ok {
+		return nil, fmt.Errorf("output %q is a %T, expected a StateSetter[%s]", name, value, reflect.TypeFor[T]())
+	}
+	return setter, nil
+}
@@ -0,0 +1,135 @@
+package subtree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/jbcpollak/greenstalk/v2/blackboard"
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+type SubTreeParams struct {
+	core.BaseParams
+
+	Library *Library
+	// Template is the name of the template in Library.
+	Template string
+	// Params override the defaults of the template's parameters.
+	Params map[string]any
+	// Remap maps the template's inputs and outputs to state of the parent
+	// tree, such as a *state.StateProvider.
+	Remap map[string]any
+	// Blackboard maps keys of the subtree's blackboard scope to keys of
+	// the parent's blackboard, see blackboard.Blackboard.Scope.
+	Blackboard map[string]string
+}
+
+// Name defaults to the name of the template.
+func (p SubTreeParams) Name() string {
+	if p.BaseParams == "" {
+		return p.Template
+	}
+	return p.BaseParams.Name()
+}
+
+func (p SubTreeParams) Validate(context.Context) error {
+	if p.Library == nil {
+		return errors.New("Library is nil")
+	}
+	if !p.Library.Defined(p.Template) {
+		return fmt.Errorf("unknown template %q", p.Template)
+	}
+	return nil
+}
+
+// SubTree builds its child from a template every time it is activated,
+// and returns the child's result. The child's names are prefixed with the
+// SubTree's, so every instance of a template has its own paths. If the
+// tree has a blackboard, the child gets a new scope of it on every
+// activation.
+func SubTree(params SubTreeParams) core.Node {
+	s := &subTree{}
+	s.DynamicDecorator = core.NewDynamicDecorator(params, s.build)
+	return s
+}
+
+type subTree struct {
+	core.DynamicDecorator[SubTreeParams]
+
+	// scope is the blackboard scope of the current activation.
+	scope *blackboard.Blackboard
+}
+
+func (s *subTree) build() (core.Node, error) {
+	def, ok := s.Params.Library.lookup(s.Params.Template)
+	if !ok {
+		return nil, fmt.Errorf("unknown template %q", s.Params.Template)
+	}
+
+	params := maps.Clone(def.defaults)
+	if params == nil {
+		params = map[string]any{}
+	}
+	maps.Copy(params, s.Params.Params)
+
+	state := maps.Clone(s.Params.Remap)
+	if state == nil {
+		state = map[string]any{}
+	}
+
+	root, err := def.template(Ports{params: params, state: state})
+	if err != nil {
+		return nil, fmt.Errorf("building template %q: %w", s.Params.Template, err)
+	}
+	if root == nil {
+		return nil, fmt.Errorf("template %q built no node", s.Params.Template)
+	}
+	return root, nil
+}
+
+func (s *subTree) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
+	child, err := s.ChildFn()
+	if err != nil {
+		return core.ErrorResult(err)
+	}
+	s.SetChild(ctx, child)
+	s.scope = nil
+
+	return s.Tick(ctx, evt)
+}
+
+func (s *subTree) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
+	if parent, ok := blackboard.FromContext(ctx); ok {
+		// A restored subtree is ticked without being activated.
+		if s.scope == nil || s.scope.Parent() != parent {
+			s.scope = parent.Scope(s.Params.Blackboard)
+		}
+		ctx = blackboard.WithContext(ctx, s.scope)
+	}
+	return core.PropagateFailure(s.Child, core.Update(ctx, s.Child, evt))
+}
+
+func (s *subTree) Leave(context.Context) error {
+	s.scope = nil
+	return nil
+}
+
+// InitInstance binds a clone's ChildFn to the clone.
+func (s *subTree) InitInstance() {
+	s.ChildFn = s.build
+	s.scope = nil
+}
+
+// CustomNode implements core.Custom, writing the node as a reference to
+// its template. The child is built on activation, so it is not written.
+func (s *subTree) CustomNode() (string, []core.Port, []core.Node) {
+	return "SubTree", []core.Port{{Name: "ID", Value: s.Params.Template}}, nil
+}
+
+var (
+	_ core.Node         = (*subTree)(nil)
+	_ core.Instantiable = (*subTree)(nil)
+	_ core.Custom       = (*subTree)(nil)
+)
@@ -0,0 +1,259 @@
+package subtree
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jbcpollak/greenstalk/v2"
+	"github.com/jbcpollak/greenstalk/v2/blackboard"
+	"github.com/jbcpollak/greenstalk/v2/common/action"
+	"github.com/jbcpollak/greenstalk/v2/common/composite"
+	"github.com/jbcpollak/greenstalk/v2/common/state"
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+func greetTemplate(ports Ports) (core.Node, error) {
+	greeting, err := Param[string](ports, "greeting")
+	if err != nil {
+		return nil, err
+	}
+	name, err := Input[string](ports, "name")
+	if err != nil {
+		return nil, err
+	}
+	said, err := Output[string](ports, "said")
+	if err != nil {
+		return nil, err
+	}
+	return composite.SequenceNamed("Greeting",
+		action.FunctionAction(action.FunctionActionParams{
+			BaseParams: "Say",
+			Func: func() core.ResultDetails {
+				said.Set(greeting + " " + name.Get())
+				return core.SuccessResult()
+			},
+		}),
+	), nil
+}
+
+func TestSubTree(t *testing.T) {
+	lib := NewLibrary()
+	if err := lib.Define("Greet", greetTemplate, map[string]any{"greeting": "hello"}); err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	name := &state.StateProvider[string]{}
+	name.Set("world")
+	said := &state.StateProvider[string]{}
+	remap := map[string]any{"name": name, "said": said}
+
+	tree, err := greenstalk.NewBehaviorTree(
+		composite.SequenceNamed("Root",
+			SubTree(SubTreeParams{Library: lib, Template: "Greet", Remap: remap}),
+			SubTree(SubTreeParams{BaseParams: "Hi", Library: lib, Template: "Greet", Params: map[string]any{"greeting": "hi"}}),
+		),
+		greenstalk.WithValidation(),
+	)
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	// The second instance has no remapped input.
+	result := tree.Update(t.Context(), core.DefaultEvent{})
+	if result.Status() != core.StatusError || !strings.Contains(result.(core.ErrorResultDetails).Err.Error(), `input "name" is not remapped`) {
+		t.Errorf("Expected the unmapped input to be reported, got %v", result)
+	}
+	if said.Get() != "hello world" {
+		t.Errorf("Expected the output to be remapped, got %q", said.Get())
+	}
+	if _, ok := tree.NodeByPath("Root.Greet.Greeting.Say"); !ok {
+		t.Errorf("Expected the subtree's nodes to be prefixed with the SubTree's name")
+	}
+}
+
+func TestRecursiveSubTree(t *testing.T) {
+	lib := NewLibrary()
+	runs := 0
+	var countdown Template = func(ports Ports) (core.Node, error) {
+		n, err := Param[int](ports, "n")
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return action.Succeed(action.SucceedParams{BaseParams: "Done"}), nil
+		}
+		return composite.Sequence(
+			action.FunctionAction(action.FunctionActionParams{
+				BaseParams: "Tick",
+				Func: func() core.ResultDetails {
+					runs++
+					return core.SuccessResult()
+				},
+			}),
+			SubTree(SubTreeParams{BaseParams: "Next", Library: lib, Template: "Countdown", Params: map[string]any{"n": n - 1}}),
+		), nil
+	}
+
+	root := SubTree(SubTreeParams{Library: lib, Template: "Countdown", Params: map[string]any{"n": 3.0}})
+	// Templates are looked up lazily, so they can be defined after use.
+	if err := lib.Define("Countdown", countdown, nil); err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	tree, err := greenstalk.NewBehaviorTree(root, greenstalk.WithValidation())
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if status := tree.Update(t.Context(), core.DefaultEvent{}).Status(); status != core.StatusSuccess {
+		t.Errorf("Expected success, got %v", status)
+	}
+	if runs != 3 {
+		t.Errorf("Expected 3 levels of recursion, got %d", runs)
+	}
+	if _, ok := tree.NodeByPath("Countdown.Sequence.Next.Sequence.Next.Sequence.Next.SucceedDone"); !ok {
+		t.Errorf("Expected nested names for recursive subtrees")
+	}
+}
+
+func TestSubTreeValidation(t *testing.T) {
+	_, err := greenstalk.NewBehaviorTree(
+		SubTree(SubTreeParams{Library: NewLibrary(), Template: "Missing"}),
+		greenstalk.WithValidation(),
+	)
+	if err == nil || !strings.Contains(err.Error(), `unknown template "Missing"`) {
+		t.Errorf("Expected the unknown template to be reported, got %v", err)
+	}
+}
+
+func TestCloneSubTree(t *testing.T) {
+	lib := NewLibrary()
+	lib.Define("Done", func(Ports) (core.Node, error) {
+		return action.Succeed(action.SucceedParams{}), nil
+	}, nil)
+
+	original := SubTree(SubTreeParams{Library: lib, Template: "Done"})
+	clone, err := core.Clone(original)
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	if status := core.Update(t.Context(), clone, core.DefaultEvent{}).Status(); status != core.StatusSuccess {
+		t.Errorf("Expected success, got %v", status)
+	}
+	if original.(*subTree).Child != nil {
+		t.Errorf("Expected the clone to build its own child")
+	}
+}
+
+var targetKey = blackboard.NewKey[string]("target")
+
+// aim copies the blackboard's target to its "aimed" key.
+type aim struct {
+	core.Leaf[core.BaseParams]
+}
+
+func (a *aim) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
+	return a.Tick(ctx, evt)
+}
+
+func (a *aim) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
+	b, ok := blackboard.FromContext(ctx)
+	if !ok {
+		return core.ErrorResult(errors.New("no blackboard"))
+	}
+	target, ok := blackboard.Get(b, targetKey)
+	if !ok {
+		return core.FailureResult()
+	}
+	blackboard.Set(b, blackboard.NewKey[string]("aimed"), target)
+	return core.SuccessResult()
+}
+
+func (a *aim) Leave(context.Context) error {
+	return nil
+}
+
+func TestSubTreeBlackboard(t *testing.T) {
+	lib := NewLibrary()
+	err := lib.Define("Aim", func(Ports) (core.Node, error) {
+		return &aim{Leaf: core.NewLeaf(core.BaseParams("Aim"))}, nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	b := blackboard.New()
+	blackboard.Set(b, blackboard.NewKey[string]("enemy"), "orc")
+	tree, err := greenstalk.NewBehaviorTree(
+		SubTree(SubTreeParams{
+			Library:    lib,
+			Template:   "Aim",
+			Blackboard: map[string]string{"target": "enemy", "aimed": "shot"},
+		}),
+		greenstalk.WithBlackboard(b),
+	)
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	if result := tree.Update(t.Context(), core.DefaultEvent{}); result.Status() != core.StatusSuccess {
+		t.Fatalf("Expected success, got %v", result)
+	}
+	if shot, _ := blackboard.Get(b, blackboard.NewKey[string]("shot")); shot != "orc" {
+		t.Errorf("Expected the subtree to write the remapped key, got %q", shot)
+	}
+	if _, ok := blackboard.Get(b, blackboard.NewKey[string]("aimed")); ok {
+		t.Errorf("Expected the subtree's own key to stay in its scope")
+	}
+}
+
+func TestSubTreeAsyncBlackboard(t *testing.T) {
+	lib := NewLibrary()
+	err := lib.Define("Aim", func(Ports) (core.Node, error) {
+		return action.AsyncFunctionAction(action.AsyncFunctionActionParams{
+			BaseParams: "Aim",
+			Func: func(ctx context.Context) core.ResultDetails {
+				b, ok := blackboard.FromContext(ctx)
+				if !ok {
+					return core.ErrorResult(errors.New("no blackboard"))
+				}
+				target, _ := blackboard.Get(b, blackboard.NewKey[string]("target"))
+				blackboard.Set(b, blackboard.NewKey[string]("aimed"), target)
+				return core.SuccessResult()
+			},
+		}), nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	b := blackboard.New()
+	blackboard.Set(b, blackboard.NewKey[string]("enemy"), "orc")
+	tree, err := greenstalk.NewBehaviorTree(
+		SubTree(SubTreeParams{
+			Library:    lib,
+			Template:   "Aim",
+			Blackboard: map[string]string{"target": "enemy", "aimed": "shot"},
+		}),
+		greenstalk.WithBlackboard(b),
+	)
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	result, err := tree.RunToCompletion(t.Context(), core.DefaultEvent{})
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if result.Status() != core.StatusSuccess {
+		t.Fatalf("Expected success, got %v", result)
+	}
+	if shot, _ := blackboard.Get(b, blackboard.NewKey[string]("shot")); shot != "orc" {
+		t.Errorf("Expected the async function to write the remapped key, got %q", shot)
+	}
+	if _, ok := blackboard.Get(b, blackboard.NewKey[string]("aimed")); ok {
+		t.Errorf("Expected the subtree's own key to stay in its scope")
+	}
+}
@@ -0,0 +1,87 @@
+package greenstalk
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+// CompletionPolicy decides what EventLoop does once the root returns
+// Success or Failure.
+type CompletionPolicy int
+
+const (
+	// CompletionContinue keeps processing events, and the next event
+	// activates the root again. This is the default.
+	CompletionContinue CompletionPolicy = iota
+	// CompletionExit makes EventLoop return nil. The final result is
+	// available from Result.
+	CompletionExit
+	// CompletionRestart activates the root again straight away, with the
+	// event EventLoop was started with.
+	CompletionRestart
+	// CompletionIdle ignores every event, except errors, until Reset is
+	// called.
+	CompletionIdle
+)
+
+// WithCompletionPolicy sets what EventLoop does once the root completes.
+func WithCompletionPolicy(policy CompletionPolicy) TreeOption {
+	return func(p *Tree) {
+		p.completion = policy
+	}
+}
+
+// resetEvent wakes up a tree idling under CompletionIdle.
+type resetEvent struct {
+	evt core.Event
+}
+
+func (e resetEvent) TargetNodeId() uuid.UUID {
+	return e.evt.TargetNodeId()
+}
+
+func (e resetEvent) ControlEvent() {}
+
+// Reset wakes up a tree idling under CompletionIdle, which then processes
+// evt. It may be called from any goroutine.
+func (bt *Tree) Reset(ctx context.Context, evt core.Event) error {
+	return bt.events.Push(ctx, resetEvent{evt})
+}
+
+// Result returns the root's result as of the last update. It is safe to
+// call while EventLoop is running.
+func (bt *Tree) Result() core.ResultDetails {
+	return *bt.result.Load()
+}
+
+// publish refreshes the index and records the root's result, so both can
+// be read from any goroutine. It must only be called by the goroutine
+// driving the tree.
+func (bt *Tree) publish() {
+	bt.index.refresh(bt.root)
+	result := bt.root.Result()
+	bt.result.Store(&result)
+}
+
+// RunToCompletion runs the event loop, starting with evt, until the root
+// returns Success or Failure, and returns that result, regardless of the
+// tree's CompletionPolicy. It is meant for job-style trees that do their
+// work once. If the loop stops for any other reason, the root's current
+// result is returned along with the reason.
+func (bt *Tree) RunToCompletion(ctx context.Context, evt core.Event) (core.ResultDetails, error) {
+	err := bt.eventLoop(ctx, evt, CompletionExit)
+	result := bt.root.Result()
+	if err != nil {
+		return result, err
+	}
+
+	if s := result.Status(); s != core.StatusSuccess && s != core.StatusFailure {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		return result, ErrTreeShutdown
+	}
+	return result, nil
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -23,6 +24,11 @@ func (c *Leaf[Params]) Walk(walkFn WalkFunc, level int) {
 	walkFn(c, level)
 }
 
+// Halt does nothing, since a leaf has no children to interrupt.
+func (c *Leaf[Params]) Halt(context.Context) error {
+	return nil
+}
+
 // String returns a string representation of the leaf node.
 func (a *Leaf[Params]) String() string {
 	return fmt.Sprintf("! %s (%v)",
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// activationTracker is implemented by every node embedding a BaseNode.
+// core.Update and core.Halt use it to scope the RunningFns a node returns
+// to the activation that returned them.
+type activationTracker interface {
+	beginActivation()
+	endActivation()
+	Generation() uint64
+	bindRunning(context.Context, InitRunningResultDetails, Event) InitRunningResultDetails
+}
+
+// Generation returns the number of times the node has been activated.
+// Nodes that enqueue completion events from a RunningFn should tag them
+// with the generation current at activation and ignore events whose
+// generation no longer matches, since those belong to an activation that
+// has been halted or left.
+func (n *BaseNode[P]) Generation() uint64 {
+	return n.generation
+}
+
+func (n *BaseNode[P]) beginActivation() {
+	n.endActivation()
+	n.generation++
+	n.activation, n.deactivate = context.WithCancel(context.Background())
+}
+
+func (n *BaseNode[P]) endActivation() {
+	if n.deactivate != nil {
+		n.deactivate()
+		n.deactivate = nil
+	}
+}
+
+// bindRunning ties a RunningFn to the node's current activation: the
+// context it runs with is cancelled when the node is halted, left or
+// re-activated, and it can no longer enqueue events afterwards. It also
+// carries the values of updateCtx, the context the node was updated with,
+// so the RunningFn sees the same blackboard scope as the node. Panics are
+// recovered according to the context's PanicPolicy, and errors are
+// attributed to the node and evt, the event it was activated with.
+func (n *BaseNode[P]) bindRunning(updateCtx context.Context, running InitRunningResultDetails, evt Event) InitRunningResultDetails {
+	if running.NodeId != uuid.Nil || n.activation == nil {
+		return running
+	}
+
+	activation := n.activation
+	values := context.WithoutCancel(updateCtx)
+	fn := running.RunningFn
+	id, fullName, category := n.id, n.FullName(), n.category
+
+	return InitRunningResultDetails{
+		NodeId:     id,
+		Generation: n.generation,
+		RunningFn: func(ctx context.Context, enqueue EnqueueFn) (err error) {
+			ctx = valuesContext{Context: ctx, values: values}
+			defer func() {
+				var nodeErr *NodeError
+				if err != nil && !errors.As(err, &nodeErr) {
+					err = &NodeError{NodeId: id, FullName: fullName, Category: category, Event: evt, Err: err}
+				}
+			}()
+			defer recoverRunning(ctx, id, fullName, panicPolicy(ctx), &err)
+
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			stop := context.AfterFunc(activation, cancel)
+			defer stop()
+
+			return fn(ctx, func(evt Event) error {
+				if err := activation.Err(); err != nil {
+					return err
+				}
+				return enqueue(evt)
+			})
+		},
+	}
+}
+
+// valuesContext is cancelled with its Context, but looks values up in
+// values first.
+type valuesContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c valuesContext) Value(key any) any {
+	if v := c.values.Value(key); v != nil {
+		return v
+	}
+	return c.Context.Value(key)
+}
+
+// bindRunningResult binds any unbound RunningFns in result to node, which
+// was updated with ctx.
+func bindRunningResult(ctx context.Context, node Node, evt Event, result ResultDetails) ResultDetails {
+	tracker, ok := node.(activationTracker)
+	if !ok {
+		return result
+	}
+
+	switch r := result.(type) {
+	case InitRunningResultDetails:
+		return tracker.bindRunning(ctx, r, evt)
+	case InitRunningResultsDetailsCollection:
+		results := make([]InitRunningResultDetails, len(r.Results))
+		for i, running := range r.Results {
+			results[i] = tracker.bindRunning(ctx, running, evt)
+		}
+		return InitRunningResultsCollection(results)
+	default:
+		return result
+	}
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// AsyncErrorPolicy decides what result a node gets when a RunningFn it
+// returned fails.
+type AsyncErrorPolicy int
+
+const (
+	// AsyncErrorResult gives the node an ErrorResult holding the error.
+	// Parents usually pass errors straight up, so unless a node handles
+	// it on the way, the error reaches the root and ends the tree.
+	AsyncErrorResult AsyncErrorPolicy = iota
+	// AsyncErrorFailure fails the node with a FailureResult carrying the
+	// error, so that parents such as a Selector or RepeatUntil can fall
+	// back or retry.
+	AsyncErrorFailure
+)
+
+type asyncErrorPolicyKey struct{}
+
+// WithAsyncErrorPolicy returns a context applying policy to the
+// RunningErrorEvents dispatched under it. The default is AsyncErrorResult.
+func WithAsyncErrorPolicy(ctx context.Context, policy AsyncErrorPolicy) context.Context {
+	return context.WithValue(ctx, asyncErrorPolicyKey{}, policy)
+}
+
+func asyncErrorPolicy(ctx context.Context) AsyncErrorPolicy {
+	policy, _ := ctx.Value(asyncErrorPolicyKey{}).(AsyncErrorPolicy)
+	return policy
+}
+
+// RunningErrorEvent delivers the error returned by a RunningFn to the node
+// that started it. Generation is the node's generation when the RunningFn
+// was returned, and the event is only applied while that activation is
+// still Running; otherwise the node is ticked with it like any other event
+// it does not recognise.
+type RunningErrorEvent struct {
+	NodeId     uuid.UUID
+	Generation uint64
+	Err        error
+}
+
+func (e RunningErrorEvent) TargetNodeId() uuid.UUID {
+	return e.NodeId
+}
+
+func (e RunningErrorEvent) ControlEvent() {}
+
+// runningError returns the result node gets for evt, and whether evt
+// applies to it at all.
+func runningError(ctx context.Context, node Node, tracker activationTracker, evt Event) (ResultDetails, bool) {
+	errEvt, ok := evt.(RunningErrorEvent)
+	if !ok || errEvt.NodeId != node.Id() || tracker == nil || tracker.Generation() != errEvt.Generation {
+		return nil, false
+	}
+	var panicErr *PanicError
+	if asyncErrorPolicy(ctx) == AsyncErrorFailure ||
+		(panicPolicy(ctx) == PanicFailNode && errors.As(errEvt.Err, &panicErr)) {
+		return FailureResultWithError(errEvt.Err), true
+	}
+	return ErrorResult(errEvt.Err), true
+}
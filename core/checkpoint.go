@@ -0,0 +1,128 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// Stateful is implemented by nodes holding runtime state beyond their
+// status and current child, such as counters, timers or the order of
+// shuffled children, so that it is included in checkpoints. SaveState
+// returns the state as JSON. RestoreState is called with it on the
+// equivalent node of a freshly built tree, after its status has been
+// restored; raw is nil when SaveState returned nil.
+//
+// A Stateful node restored as Running is expected to resume on its next
+// Tick. Any RunningFn it had started is gone, so it must return a new one
+// from Tick if it needs to wait for something again.
+type Stateful interface {
+	SaveState() (json.RawMessage, error)
+	RestoreState(raw json.RawMessage) error
+}
+
+// NodeState is the runtime state of a node, see CheckpointNode.
+type NodeState struct {
+	// Name is the node's FullName, used to check that a checkpoint is
+	// restored into a tree of the same shape.
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	// CurrentChild is the child a composite is at.
+	CurrentChild int `json:"currentChild,omitempty"`
+	// DynamicChild is set when a DynamicDecorator has created its child.
+	DynamicChild bool `json:"dynamicChild,omitempty"`
+	// State is the state saved by a Stateful node.
+	State json.RawMessage `json:"state,omitempty"`
+}
+
+// baseState is implemented by the base node types holding runtime state
+// of their own, to save and restore it.
+type baseState interface {
+	saveBase(*NodeState)
+	restoreBase(NodeState) error
+}
+
+// CheckpointNode returns the runtime state of node, without its children.
+func CheckpointNode(node Node) (NodeState, error) {
+	state := NodeState{Name: node.FullName(), Status: node.Result().Status()}
+	if base, ok := node.(baseState); ok {
+		base.saveBase(&state)
+	}
+	if stateful, ok := node.(Stateful); ok {
+		raw, err := stateful.SaveState()
+		if err != nil {
+			return state, err
+		}
+		state.State = raw
+	}
+	return state, nil
+}
+
+// RestoreNode restores state, as returned by CheckpointNode, into node.
+// Only statuses are restored, not result details. Leaves that were
+// Running are restored as not Running, unless they are Stateful, so they
+// are activated again and restart whatever work they were doing; errored
+// nodes are restored as not Running either.
+func RestoreNode(node Node, state NodeState) error {
+	result := restoredResult(node, state.Status)
+	if tracker, ok := node.(activationTracker); ok && result.Status() == StatusRunning {
+		tracker.beginActivation()
+	}
+	node.SetResult(result)
+
+	var errs []error
+	if base, ok := node.(baseState); ok {
+		errs = append(errs, base.restoreBase(state))
+	}
+	if stateful, ok := node.(Stateful); ok {
+		errs = append(errs, stateful.RestoreState(state.State))
+	}
+	return errors.Join(errs...)
+}
+
+func restoredResult(node Node, status Status) ResultDetails {
+	switch status {
+	case StatusSuccess:
+		return SuccessResult()
+	case StatusFailure:
+		return FailureResult()
+	case StatusRunning:
+		if _, ok := node.(Stateful); ok || node.Category() != CategoryLeaf {
+			return RunningResult()
+		}
+	}
+	return InvalidResult()
+}
+
+func (c *Composite[P]) saveBase(state *NodeState) {
+	state.CurrentChild = c.CurrentChild
+}
+
+func (c *Composite[P]) restoreBase(state NodeState) error {
+	if state.CurrentChild < 0 || state.CurrentChild > len(c.Children) {
+		return errors.New("current child out of range")
+	}
+	c.CurrentChild = state.CurrentChild
+	return nil
+}
+
+func (d *DynamicDecorator[P]) saveBase(state *NodeState) {
+	state.DynamicChild = d.Child != nil
+}
+
+// restoreBase recreates the child the decorator had, so that the child's
+// own state can be restored next.
+func (d *DynamicDecorator[P]) restoreBase(state NodeState) error {
+	if !state.DynamicChild || d.Child != nil {
+		return nil
+	}
+	if d.ChildFn == nil {
+		return errors.New("ChildFn is nil")
+	}
+	child, err := d.ChildFn()
+	if err != nil {
+		return err
+	}
+	d.SetChild(context.Background(), child)
+	return nil
+}
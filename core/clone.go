@@ -0,0 +1,102 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/google/uuid"
+)
+
+// Instantiable is implemented by nodes whose runtime state holds
+// references, such as slices or maps, that copies made by Clone must not
+// share. InitInstance is called on every copy, after its children have
+// been cloned, to allocate its own.
+type Instantiable interface {
+	InitInstance()
+}
+
+// cloneable is implemented by the base node types, to give a copy its own
+// id, runtime state and children.
+type cloneable interface {
+	cloneBase() error
+}
+
+// Clone returns a copy of the tree rooted at node. The copy shares the
+// configuration of the original, such as its Params and the functions
+// passed to constructors, but has its own ids and runtime state. Params
+// are held by pointer, so they are not copied, and must not be modified
+// once a node is built. The original must not be running, since its
+// runtime state is copied as the initial state of the copy. Nodes must be
+// pointers to structs embedding one of the base node types.
+func Clone(node Node) (Node, error) {
+	v := reflect.ValueOf(node)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot clone node %s of type %T", node.Name(), node)
+	}
+	if node.Result().Status() == StatusRunning {
+		return nil, fmt.Errorf("cannot clone node %s while it is running", node.FullName())
+	}
+
+	copied := reflect.New(v.Elem().Type())
+	copied.Elem().Set(v.Elem())
+	clone := copied.Interface().(Node)
+
+	base, ok := clone.(cloneable)
+	if !ok {
+		return nil, fmt.Errorf("cannot clone node %s of type %T", node.Name(), node)
+	}
+	if err := base.cloneBase(); err != nil {
+		return nil, err
+	}
+	if inst, ok := clone.(Instantiable); ok {
+		inst.InitInstance()
+	}
+	return clone, nil
+}
+
+func (n *BaseNode[P]) cloneBase() error {
+	n.id = uuid.New()
+	n.result = InvalidResult()
+	n.generation = 0
+	n.activation, n.deactivate = nil, nil
+	return nil
+}
+
+func (c *Composite[P]) cloneBase() error {
+	c.BaseNode.cloneBase()
+	c.CurrentChild = 0
+
+	children := make([]Node, len(c.Children))
+	for i, child := range c.Children {
+		if child == nil {
+			continue
+		}
+		clone, err := Clone(child)
+		if err != nil {
+			return err
+		}
+		children[i] = clone
+	}
+	c.Children = children
+	return nil
+}
+
+func (d *Decorator[P]) cloneBase() error {
+	d.BaseNode.cloneBase()
+	if d.Child == nil {
+		return nil
+	}
+	clone, err := Clone(d.Child)
+	if err != nil {
+		return err
+	}
+	d.Child = clone
+	return nil
+}
+
+// cloneBase drops the child, which the copy creates again with ChildFn.
+func (d *DynamicDecorator[P]) cloneBase() error {
+	d.BaseNode.cloneBase()
+	d.Child = nil
+	return nil
+}
@@ -1,5 +1,10 @@
 package core
 
+import (
+	"context"
+	"errors"
+)
+
 // Composite is the base type for any specific composite node. Such a node
 // may be domain-specific, but usually one of the common nodes will be used,
 // such as Sequence or Selector.
@@ -12,7 +17,9 @@ type Composite[P Params] struct {
 // NewComposite creates a new composite base node.
 func NewComposite[P Params](params P, children []Node) Composite[P] {
 	for _, child := range children {
-		child.SetNamePrefix(params.Name())
+		if child != nil {
+			child.SetNamePrefix(params.Name())
+		}
 	}
 	return Composite[P]{
 		BaseNode: newBaseNode(CategoryComposite, params),
@@ -23,8 +30,22 @@ func NewComposite[P Params](params P, children []Node) Composite[P] {
 func (c *Composite[P]) Walk(walkFn WalkFunc, level int) {
 	walkFn(c, level)
 	for _, child := range c.Children {
-		child.Walk(walkFn, level+1)
+		if child != nil {
+			child.Walk(walkFn, level+1)
+		}
+	}
+}
+
+// Halt halts every Running child and rewinds CurrentChild.
+func (c *Composite[P]) Halt(ctx context.Context) error {
+	var errs []error
+	for _, child := range c.Children {
+		if child != nil {
+			errs = append(errs, Halt(ctx, child))
+		}
 	}
+	c.CurrentChild = 0
+	return errors.Join(errs...)
 }
 
 // String returns a string representation of the composite node.
@@ -35,6 +56,8 @@ func (c *Composite[P]) String() string {
 func (c *Composite[P]) SetNamePrefix(namePrefix string) {
 	c.BaseNode.SetNamePrefix(namePrefix)
 	for _, child := range c.Children {
-		child.SetNamePrefix(c.FullName())
+		if child != nil {
+			child.SetNamePrefix(c.FullName())
+		}
 	}
 }
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -17,7 +18,9 @@ type Decorator[P Params] struct {
 
 // NewDecorator creates a new decorator base node.
 func NewDecorator[P Params](params P, child Node) Decorator[P] {
-	child.SetNamePrefix(params.Name())
+	if child != nil {
+		child.SetNamePrefix(params.Name())
+	}
 	return Decorator[P]{
 		BaseNode: newBaseNode(CategoryDecorator, params),
 		Child:    child,
@@ -26,7 +29,17 @@ func NewDecorator[P Params](params P, child Node) Decorator[P] {
 
 func (c *Decorator[P]) Walk(walkFn WalkFunc, level int) {
 	walkFn(c, level)
-	c.Child.Walk(walkFn, level+1)
+	if c.Child != nil {
+		c.Child.Walk(walkFn, level+1)
+	}
+}
+
+// Halt halts the child if it is Running.
+func (d *Decorator[P]) Halt(ctx context.Context) error {
+	if d.Child == nil {
+		return nil
+	}
+	return Halt(ctx, d.Child)
 }
 
 // String returns a string representation of the decorator node.
@@ -36,5 +49,7 @@ func (d *Decorator[P]) String() string {
 
 func (d *Decorator[P]) SetNamePrefix(namePrefix string) {
 	d.BaseNode.SetNamePrefix(namePrefix)
-	d.Child.SetNamePrefix(d.FullName())
+	if d.Child != nil {
+		d.Child.SetNamePrefix(d.FullName())
+	}
 }
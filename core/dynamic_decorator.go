@@ -1,6 +1,9 @@
 package core
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 type DynamicDecorator[P Params] struct {
 	BaseNode[P]
@@ -15,6 +18,14 @@ func NewDynamicDecorator[P Params](params P, childFn func() (Node, error)) Dynam
 	}
 }
 
+// SetChild replaces the decorator's child and notifies the tree that
+// its structure has changed.
+func (d *DynamicDecorator[P]) SetChild(ctx context.Context, child Node) {
+	child.SetNamePrefix(d.FullName())
+	d.Child = child
+	StructureChanged(ctx)
+}
+
 func (c *DynamicDecorator[P]) Walk(walkFn WalkFunc, level int) {
 	walkFn(c, level)
 	if c.Child != nil {
@@ -22,6 +33,14 @@ func (c *DynamicDecorator[P]) Walk(walkFn WalkFunc, level int) {
 	}
 }
 
+// Halt halts the current child, if any, when it is Running.
+func (d *DynamicDecorator[P]) Halt(ctx context.Context) error {
+	if d.Child == nil {
+		return nil
+	}
+	return Halt(ctx, d.Child)
+}
+
 func (d *DynamicDecorator[P]) String() string {
 	return fmt.Sprintf("*d %s (%v)", d.Params.Name(), d.Params)
 }
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"github.com/google/uuid"
+)
+
+// FailureResultDetails is a failure that says why the node failed.
+type FailureResultDetails struct {
+	Reason string
+	Err    error
+	// Causes holds the results of the children that led to the failure.
+	Causes []Cause
+}
+
+func (FailureResultDetails) Status() Status   { return StatusFailure }
+func (d FailureResultDetails) Error() error   { return d.Err }
+func (d FailureResultDetails) String() string { return d.Reason }
+
+// Cause is the result a child node had when its parent failed because
+// of it.
+type Cause struct {
+	NodeId   uuid.UUID
+	FullName string
+	Result   ResultDetails
+}
+
+// CauseOf records node's current result as a Cause.
+func CauseOf(node Walkable) Cause {
+	return Cause{
+		NodeId:   node.Id(),
+		FullName: node.FullName(),
+		Result:   node.Result(),
+	}
+}
+
+// FailureResultWithReason returns a failure explained by reason.
+func FailureResultWithReason(reason string) FailureResultDetails {
+	return FailureResultDetails{Reason: reason}
+}
+
+// FailureResultWithError returns a failure explained by err.
+func FailureResultWithError(err error) FailureResultDetails {
+	return FailureResultDetails{Reason: err.Error(), Err: err}
+}
+
+// FailureCausedBy returns a failure caused by the given children, which
+// is what composites return when their children's failures make them fail.
+func FailureCausedBy(reason string, children ...Walkable) FailureResultDetails {
+	causes := make([]Cause, len(children))
+	for i, child := range children {
+		causes[i] = CauseOf(child)
+	}
+	return FailureResultDetails{Reason: reason, Causes: causes}
+}
+
+// PropagateFailure returns result, the result child was just updated
+// with, as the result of its parent. A failure is wrapped so that the
+// parent's result records the child as its cause, any other result is
+// returned as is.
+func PropagateFailure(child Walkable, result ResultDetails) ResultDetails {
+	if result.Status() != StatusFailure {
+		return result
+	}
+	return FailureCausedBy("child "+child.Name()+" failed", child)
+}
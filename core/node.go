@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -34,6 +35,21 @@ func (e targetNodeEvent) TargetNodeId() uuid.UUID {
 	return e.targetNodeId
 }
 
+// TickEvent is broadcast by a clock-driven tree at every tick of its clock.
+type TickEvent struct {
+	// Tick numbers the clock ticks, starting at 1.
+	Tick uint64
+	// Time is the wall time at which the tick fired.
+	Time time.Time
+	// Delta is the time elapsed since the previous tick, or since the
+	// clock was started for the first tick.
+	Delta time.Duration
+}
+
+func (e TickEvent) TargetNodeId() uuid.UUID {
+	return uuid.Nil
+}
+
 type ErrorEvent struct {
 	Err error
 }
@@ -42,6 +58,29 @@ func (e ErrorEvent) TargetNodeId() uuid.UUID {
 	return uuid.Nil
 }
 
+func (e ErrorEvent) ControlEvent() {}
+
+// ControlEvent is implemented by the events the library relies on to make
+// progress, such as those completing asynchronous work, resuming Running
+// nodes or reporting errors. Event queues never drop or expire them,
+// whatever their overflow policy or TTL.
+type ControlEvent interface {
+	Event
+	ControlEvent()
+}
+
+// ResumeEvent returns a control event targeting the node with the given
+// id, which nodes enqueue from their RunningFns to be updated again.
+func ResumeEvent(id uuid.UUID) ControlEvent {
+	return resumeEvent{targetNodeEvent{targetNodeId: id}}
+}
+
+type resumeEvent struct {
+	targetNodeEvent
+}
+
+func (e resumeEvent) ControlEvent() {}
+
 // Preliminary interface to work around intermediate types like
 // composite, decorator, etc not implementing Enter/Tick/Leave
 type Walkable interface {
@@ -72,6 +111,12 @@ type Node interface {
 	Tick(context.Context, Event) ResultDetails
 	Leave(context.Context) error
 	SetNamePrefix(string)
+
+	// Halt interrupts any Running children of the node. It is called
+	// by core.Halt when the node is preempted and by core.Update when
+	// the node completes, in both cases before Leave. A default is
+	// provided by embedding a Composite, Decorator or Leaf node.
+	Halt(context.Context) error
 }
 
 type Params interface {
@@ -81,11 +126,26 @@ type Params interface {
 // BaseNode contains properties shared by all categories of node.
 // Do not use this type directly.
 type BaseNode[P Params] struct {
+	// nodeConfig holds the Params, which are shared with the copies
+	// made by Clone. The other fields are the node's own.
+	*nodeConfig[P]
+
 	id         uuid.UUID
-	category   Category
 	result     ResultDetails
-	Params     P
 	namePrefix string
+
+	// generation counts activations, and activation is cancelled
+	// as soon as the current one ends. See activation.go.
+	generation uint64
+	activation context.Context
+	deactivate context.CancelFunc
+}
+
+// nodeConfig is the configuration of a node, which doesn't change once it
+// is built.
+type nodeConfig[P Params] struct {
+	category Category
+	Params   P
 }
 
 func newBaseNode[P Params](category Category, params P) BaseNode[P] {
@@ -94,10 +154,9 @@ func newBaseNode[P Params](category Category, params P) BaseNode[P] {
 		panic(err)
 	}
 	return BaseNode[P]{
+		nodeConfig: &nodeConfig[P]{category: category, Params: params},
 		id:         uuid.New(),
-		category:   category,
 		result:     InvalidResult(),
-		Params:     params,
 		namePrefix: "",
 	}
 }
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// NodeError is an error attributed to the node that produced it. Errors
+// returned through ErrorResult, Leave or a RunningFn are wrapped in a
+// NodeError by the innermost node they come from and keep that
+// attribution as they propagate up the tree, so errors.As can be used on
+// the error returned by EventLoop to find out where it came from.
+type NodeError struct {
+	NodeId   uuid.UUID
+	FullName string
+	Category Category
+	// Event is the event being processed when the error occurred. For
+	// errors returned by a RunningFn it is the event that activated the
+	// node, and it is nil for errors raised while halting.
+	Event Event
+	Err   error
+}
+
+// NewNodeError attributes err to node.
+func NewNodeError(node Walkable, evt Event, err error) *NodeError {
+	return &NodeError{
+		NodeId:   node.Id(),
+		FullName: node.FullName(),
+		Category: node.Category(),
+		Event:    evt,
+		Err:      err,
+	}
+}
+
+func (e *NodeError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Category, e.FullName, e.Err)
+}
+
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// attributeError wraps err in a NodeError for node, unless it is nil or
+// has already been attributed to a node.
+func attributeError(node Walkable, evt Event, err error) error {
+	var nodeErr *NodeError
+	if err == nil || errors.As(err, &nodeErr) {
+		return err
+	}
+	return NewNodeError(node, evt, err)
+}
+
+// attributeErrorResult attributes the error held by an ErrorResult to node.
+func attributeErrorResult(node Walkable, evt Event, result ResultDetails) ResultDetails {
+	if r, ok := result.(ErrorResultDetails); ok && r.Err != nil {
+		return ErrorResult(attributeError(node, evt, r.Err))
+	}
+	return result
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/google/uuid"
+	"github.com/jbcpollak/greenstalk/v2/internal"
+)
+
+// PanicPolicy decides what happens when a node panics in one of its
+// callbacks, or in a RunningFn it returned.
+type PanicPolicy int
+
+const (
+	// PanicCrash lets the panic propagate, crashing the process unless
+	// something further up recovers it.
+	PanicCrash PanicPolicy = iota
+	// PanicFailNode recovers the panic and fails the node that panicked.
+	// A panicking RunningFn returns a *PanicError, which fails the node
+	// that started it once delivered as a RunningErrorEvent.
+	PanicFailNode
+	// PanicErrorTree recovers the panic and turns it into an ErrorResult
+	// holding a *PanicError, which errors the tree.
+	PanicErrorTree
+)
+
+type panicPolicyKey struct{}
+
+// WithPanicPolicy returns a context applying policy to Update, Halt and
+// the RunningFns started under it. Without it, panics are not recovered.
+func WithPanicPolicy(ctx context.Context, policy PanicPolicy) context.Context {
+	return context.WithValue(ctx, panicPolicyKey{}, policy)
+}
+
+func panicPolicy(ctx context.Context) PanicPolicy {
+	policy, _ := ctx.Value(panicPolicyKey{}).(PanicPolicy)
+	return policy
+}
+
+// PanicError is a recovered panic, attributed to the node it came from.
+type PanicError struct {
+	NodeId   uuid.UUID
+	FullName string
+	Value    any
+	Stack    []byte
+}
+
+func newPanicError(id uuid.UUID, fullName string, value any) *PanicError {
+	return &PanicError{
+		NodeId:   id,
+		FullName: fullName,
+		Value:    value,
+		Stack:    debug.Stack(),
+	}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// recoverUpdate turns a panic raised while updating node into a result,
+// according to policy. It must be deferred directly.
+func recoverUpdate(ctx context.Context, node Node, evt Event, policy PanicPolicy, result *ResultDetails) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	err := newPanicError(node.Id(), node.FullName(), r)
+	internal.Logger.ErrorContext(ctx, "Recovered panic in node", "node", err.FullName, "panic", r, "stack", string(err.Stack))
+
+	// The node is left as it is, since its own callbacks can no longer be
+	// trusted, but whatever it was running is stopped.
+	haltAfterPanic(ctx, node)
+	if tracker, ok := node.(activationTracker); ok {
+		tracker.endActivation()
+	}
+
+	if policy == PanicFailNode {
+		*result = FailureResultWithError(err)
+	} else {
+		*result = ErrorResult(NewNodeError(node, evt, err))
+	}
+	node.SetResult(*result)
+}
+
+func haltAfterPanic(ctx context.Context, node Node) {
+	defer func() {
+		if r := recover(); r != nil {
+			internal.Logger.ErrorContext(ctx, "Recovered panic while halting node", "node", node.FullName(), "panic", r)
+		}
+	}()
+	if err := node.Halt(ctx); err != nil {
+		internal.Logger.ErrorContext(ctx, "Error while halting node", "node", node.FullName(), "err", err)
+	}
+}
+
+// recoverRunning turns a panic raised by a RunningFn into an error, unless
+// policy is PanicCrash. It must be deferred directly.
+func recoverRunning(ctx context.Context, id uuid.UUID, fullName string, policy PanicPolicy, err *error) {
+	if policy == PanicCrash {
+		return
+	}
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	panicErr := newPanicError(id, fullName, r)
+	internal.Logger.ErrorContext(ctx, "Recovered panic in running function", "node", panicErr.FullName, "panic", r, "stack", string(panicErr.Stack))
+	*err = panicErr
+}
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+type routeKey struct{}
+
+// route is the chain of node ids from the root of a tree down to the
+// target of the event currently being dispatched.
+type route []uuid.UUID
+
+// WithEventRoute returns a context that restricts Update to the nodes on
+// path, which must list the ids from the root down to the event's target.
+// Running nodes off the path are not ticked and keep reporting Running,
+// while nodes that are not running are activated as usual so composites
+// can still move on to their next child. Below the target no restriction
+// applies.
+func WithEventRoute(ctx context.Context, path []uuid.UUID) context.Context {
+	return context.WithValue(ctx, routeKey{}, route(path))
+}
+
+// routed reports whether node should be ticked under the route in ctx,
+// and returns the context to pass down to its children.
+func routed(ctx context.Context, node Node) (context.Context, bool) {
+	r, ok := ctx.Value(routeKey{}).(route)
+	if !ok || len(r) == 0 {
+		return ctx, true
+	}
+	if node.Id() == r[len(r)-1] {
+		return context.WithValue(ctx, routeKey{}, route(nil)), true
+	}
+	if node.Result().Status() != StatusRunning {
+		return ctx, true
+	}
+	return ctx, slices.Contains(r, node.Id())
+}
@@ -0,0 +1,23 @@
+package core
+
+// Port is a named parameter of a node, with its value as text.
+type Port struct {
+	Name  string
+	Value string
+}
+
+// Standard is implemented by nodes equivalent to one of the standard nodes
+// of BehaviorTree.CPP, so that exporters can write them as such.
+// StandardNode returns the equivalent node's type, such as "Sequence",
+// and the values of its ports.
+type Standard interface {
+	StandardNode() (typ string, ports []Port)
+}
+
+// Custom is implemented by nodes that are not written to documents as
+// their Go type, such as nodes registered under another name or built
+// around internal children. CustomNode returns the type to write, such as
+// "WithAsync", the values of its ports and the children to write.
+type Custom interface {
+	CustomNode() (typ string, ports []Port, children []Node)
+}
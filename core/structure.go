@@ -0,0 +1,20 @@
+package core
+
+import "context"
+
+type structureListenerKey struct{}
+
+// WithStructureListener returns a context under which fn is called
+// whenever a node replaces one of its children, so that anything indexing
+// the tree can refresh.
+func WithStructureListener(ctx context.Context, fn func()) context.Context {
+	return context.WithValue(ctx, structureListenerKey{}, fn)
+}
+
+// StructureChanged notifies the listener registered in ctx, if any, that
+// the structure of the tree has changed.
+func StructureChanged(ctx context.Context) {
+	if fn, ok := ctx.Value(structureListenerKey{}).(func()); ok {
+		fn()
+	}
+}
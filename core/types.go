@@ -3,6 +3,8 @@ package core
 import (
 	"context"
 	"fmt"
+
+	"github.com/google/uuid"
 )
 
 // Category denotes whether a node is a composite, decorator or leaf.
@@ -60,11 +62,19 @@ type (
 )
 
 func InitRunningResult(fn RunningFn) InitRunningResultDetails {
-	return InitRunningResultDetails{fn}
+	return InitRunningResultDetails{RunningFn: fn}
 }
 
 type InitRunningResultDetails struct {
 	RunningFn RunningFn
+
+	// NodeId is the node that returned the RunningFn. It is set by
+	// core.Update, which also scopes the RunningFn to the node's
+	// current activation and runs it with the values, such as the
+	// blackboard, of the context the node was updated with.
+	NodeId uuid.UUID
+	// Generation is the node's generation when it returned the RunningFn.
+	Generation uint64
 }
 
 func (InitRunningResultDetails) Status() Status { return StatusRunning }
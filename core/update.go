@@ -2,30 +2,87 @@ package core
 
 import (
 	"context"
+	"errors"
 )
 
 // Update updates a node by calling its Enter method if it is not running,
 // then its Tick method, and finally Leave if it is not still running.
-func Update(ctx context.Context, node Node, evt Event) ResultDetails {
-	var result ResultDetails
+// Any children the node leaves Running when it completes, including with
+// an error, are halted before the node itself is left.
+//
+// Each activation gets its own scope: RunningFns returned by the node are
+// cancelled once it is left, halted or activated again.
+//
+// If ctx carries an event route (see WithEventRoute) and node is Running
+// but not on it, the node is skipped and RunningResult is returned.
+//
+// If ctx carries a PanicPolicy (see WithPanicPolicy), panics raised by the
+// node are recovered and turned into its result.
+//
+// A RunningErrorEvent for the node's current activation is not passed to
+// Tick: the node completes with the error instead, as an ErrorResult or a
+// FailureResult depending on the ctx's AsyncErrorPolicy.
+func Update(ctx context.Context, node Node, evt Event) (result ResultDetails) {
+	ctx, ok := routed(ctx, node)
+	if !ok {
+		return RunningResult()
+	}
+
+	if policy := panicPolicy(ctx); policy != PanicCrash {
+		defer recoverUpdate(ctx, node, evt, policy, &result)
+	}
+
+	tracker, tracked := node.(activationTracker)
 
 	if node.Result().Status() != StatusRunning {
+		if tracked {
+			tracker.beginActivation()
+		}
 		result = node.Activate(ctx, evt)
+	} else if errResult, ok := runningError(ctx, node, tracker, evt); ok {
+		result = errResult
 	} else {
 		result = node.Tick(ctx, evt)
 	}
 
+	result = bindRunningResult(ctx, node, evt, result)
+	result = attributeErrorResult(node, evt, result)
 	node.SetResult(result)
 
-	if s := result.Status(); s == StatusError || s == StatusRunning {
+	if result.Status() == StatusRunning {
 		return result
 	}
 
-	err := node.Leave(ctx)
+	err := errors.Join(node.Halt(ctx), attributeError(node, evt, node.Leave(ctx)))
+	if tracked {
+		tracker.endActivation()
+	}
 	if err != nil {
+		if errResult, ok := result.(ErrorResultDetails); ok {
+			err = errors.Join(errResult.Err, err)
+		}
 		result = ErrorResult(err)
 		node.SetResult(result)
 	}
 
 	return result
 }
+
+// Halt preempts a node that is still running. Its Running descendants
+// are halted first, then the node is left, the RunningFns it started are
+// cancelled and its result is reset to StatusInvalid so that the next
+// Update activates it from scratch.
+// Halt does nothing if the node is not running.
+func Halt(ctx context.Context, node Node) error {
+	if node.Result().Status() != StatusRunning {
+		return nil
+	}
+
+	err := errors.Join(node.Halt(ctx), attributeError(node, nil, node.Leave(ctx)))
+	if tracker, ok := node.(activationTracker); ok {
+		tracker.endActivation()
+	}
+	node.SetResult(InvalidResult())
+
+	return err
+}
@@ -0,0 +1,67 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Validator can be implemented by nodes, and by their Params, to check
+// their configuration before the tree runs. Composite, Decorator and Leaf
+// nodes provide a Validate method checking their structure and Params;
+// nodes overriding it should call the embedded one.
+type Validator interface {
+	Validate(context.Context) error
+}
+
+func validateParams[P Params](ctx context.Context, params P) error {
+	if v, ok := any(params).(Validator); ok {
+		if err := v.Validate(ctx); err != nil {
+			return fmt.Errorf("invalid params: %w", err)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the composite has children, none of them nil,
+// and that its Params are valid.
+func (c *Composite[P]) Validate(ctx context.Context) error {
+	var errs []error
+	if len(c.Children) == 0 {
+		errs = append(errs, errors.New("composite has no children"))
+	}
+	for i, child := range c.Children {
+		if child == nil {
+			errs = append(errs, fmt.Errorf("child %d is nil", i))
+		}
+	}
+	errs = append(errs, validateParams(ctx, c.Params))
+	return errors.Join(errs...)
+}
+
+// Validate checks that the decorator has a child and that its Params are
+// valid.
+func (d *Decorator[P]) Validate(ctx context.Context) error {
+	var errs []error
+	if d.Child == nil {
+		errs = append(errs, errors.New("decorator child is nil"))
+	}
+	errs = append(errs, validateParams(ctx, d.Params))
+	return errors.Join(errs...)
+}
+
+// Validate checks that the decorator has a function to create its child
+// and that its Params are valid.
+func (d *DynamicDecorator[P]) Validate(ctx context.Context) error {
+	var errs []error
+	if d.ChildFn == nil {
+		errs = append(errs, errors.New("dynamic decorator child function is nil"))
+	}
+	errs = append(errs, validateParams(ctx, d.Params))
+	return errors.Join(errs...)
+}
+
+// Validate checks that the leaf's Params are valid.
+func (c *Leaf[P]) Validate(ctx context.Context) error {
+	return validateParams(ctx, c.Params)
+}
@@ -0,0 +1,46 @@
+package core
+
+// ChildNodes returns the composite's children.
+func (c *Composite[P]) ChildNodes() []Node {
+	return c.Children
+}
+
+// ChildNodes returns the decorator's child.
+func (d *Decorator[P]) ChildNodes() []Node {
+	return []Node{d.Child}
+}
+
+// ChildNodes returns the decorator's current child, if it has been
+// created yet.
+func (d *DynamicDecorator[P]) ChildNodes() []Node {
+	if d.Child == nil {
+		return nil
+	}
+	return []Node{d.Child}
+}
+
+// ChildNodes returns nothing, since a leaf has no children.
+func (c *Leaf[P]) ChildNodes() []Node {
+	return nil
+}
+
+// WalkNodes calls fn for node and each of its descendants, depth first.
+// Unlike Walk, which visits the Composite, Decorator or Leaf embedded in
+// each node, fn receives the nodes themselves, so it sees the methods a
+// custom node defines or overrides. Nil children are skipped.
+func WalkNodes(node Node, fn func(node Node, level int)) {
+	walkNodes(node, fn, 0)
+}
+
+func walkNodes(node Node, fn func(Node, int), level int) {
+	fn(node, level)
+	parent, ok := node.(interface{ ChildNodes() []Node })
+	if !ok {
+		return
+	}
+	for _, child := range parent.ChildNodes() {
+		if child != nil {
+			walkNodes(child, fn, level+1)
+		}
+	}
+}
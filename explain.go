@@ -0,0 +1,61 @@
+package greenstalk
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+// ExplanationStep is a node that contributed to a result, see Explain.
+type ExplanationStep struct {
+	NodeId uuid.UUID
+	Path   string
+	Status core.Status
+	Reason string
+	Err    error
+	// Level is the depth of the step in the explanation, the root being
+	// at level 0 and each cause one level below the node it caused.
+	Level int
+}
+
+// Explain returns the chain of nodes and reasons that produced the root's
+// last result, root first. Failures carrying causes, such as those returned
+// by the composites in common/composite, are followed down to the leaves
+// that started them; a Selector contributes one branch per failed child.
+// It is safe to call while EventLoop is running.
+func (bt *Tree) Explain() []ExplanationStep {
+	var steps []ExplanationStep
+	_ = bt.Do(context.Background(), func() {
+		steps = explain(core.CauseOf(bt.root), 0, nil)
+	})
+	return steps
+}
+
+func explain(cause core.Cause, level int, steps []ExplanationStep) []ExplanationStep {
+	step := ExplanationStep{
+		NodeId: cause.NodeId,
+		Path:   cause.FullName,
+		Status: cause.Result.Status(),
+		Level:  level,
+	}
+
+	var causes []core.Cause
+	switch r := cause.Result.(type) {
+	case core.FailureResultDetails:
+		step.Reason = r.Reason
+		step.Err = r.Err
+		causes = r.Causes
+	case core.ErrorResultDetails:
+		step.Err = r.Err
+		if r.Err != nil {
+			step.Reason = r.Err.Error()
+		}
+	}
+
+	steps = append(steps, step)
+	for _, c := range causes {
+		steps = explain(c, level+1, steps)
+	}
+	return steps
+}
@@ -7,6 +7,8 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/pkg/errors v0.9.1
 	github.com/sergi/go-diff v1.4.0
+	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
@@ -4,9 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/google/uuid"
+
+	"github.com/jbcpollak/greenstalk/v2/blackboard"
 	"github.com/jbcpollak/greenstalk/v2/core"
 	"github.com/jbcpollak/greenstalk/v2/internal"
+	"github.com/jbcpollak/greenstalk/v2/queue"
 	"github.com/jbcpollak/greenstalk/v2/util"
 )
 
@@ -14,9 +21,41 @@ import (
 //
 // It must be initialized by calling [NewBehaviorTree].
 type Tree struct {
-	root     core.Node
-	events   chan core.Event
-	visitors []core.Visitor
+	root       core.Node
+	events     queue.EventQueue
+	visitors   []core.Visitor
+	clock      *tickClock
+	completion CompletionPolicy
+	panics     core.PanicPolicy
+	asyncErrs  core.AsyncErrorPolicy
+	blackboard *blackboard.Blackboard
+	validate   bool
+
+	// optionErrs collects invalid options, reported by NewBehaviorTree.
+	optionErrs []error
+
+	// index locates nodes by id and name, see index.go.
+	index nodeIndex
+	// result is the root's result as of the last update, see
+	// completion.go.
+	result atomic.Pointer[core.ResultDetails]
+
+	// running tracks the goroutines executing RunningFns, and
+	// runningCtx is cancelled by Shutdown to stop them, including any
+	// blocked pushing to a full queue.
+	running     sync.WaitGroup
+	runningCtx  context.Context
+	stopRunning context.CancelFunc
+
+	// requests carries the functions passed to Do, see inspect.go.
+	requests chan doRequest
+
+	// Shutdown state, see shutdown.go.
+	mu          sync.Mutex
+	stop        chan struct{}
+	loopDone    chan struct{}
+	isShutdown  bool
+	shutdownErr []error
 }
 
 func NewBehaviorTree(
@@ -33,22 +72,69 @@ func NewBehaviorTree(
 		return nil, eb.Error()
 	}
 
+	tree := newTree(root, opts)
+	if err := errors.Join(tree.optionErrs...); err != nil {
+		return nil, err
+	}
+
+	if tree.validate {
+		if err := tree.Validate(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
+	tree.index.build(root)
+	tree.publish()
+
+	return tree, nil
+}
+
+func newTree(root core.Node, opts []TreeOption) *Tree {
 	tree := &Tree{
-		root:   root,
-		events: make(chan core.Event, 100 /* arbitrary */),
+		root:     root,
+		events:   queue.NewBounded(100 /* arbitrary */, queue.Block),
+		stop:     make(chan struct{}),
+		requests: make(chan doRequest),
+		panics:   core.PanicErrorTree,
 	}
+	tree.runningCtx, tree.stopRunning = context.WithCancel(context.Background())
 
 	// Apply all options to the tree.
 	for _, opt := range opts {
 		opt(tree)
 	}
+	return tree
+}
 
-	return tree, nil
+// route returns a context restricting the update to the path leading to
+// the event's target. Broadcast events, and events whose target is not
+// part of the tree, are dispatched to the whole tree.
+func (bt *Tree) route(ctx context.Context, evt core.Event) context.Context {
+	target := evt.TargetNodeId()
+	if target == uuid.Nil {
+		return ctx
+	}
+
+	path, ok := bt.index.path(target)
+	if !ok {
+		return ctx
+	}
+
+	return core.WithEventRoute(ctx, path)
 }
 
-// Update propagates an update call down the behavior tree.
+// Update propagates an update call down the behavior tree. Events with a
+// TargetNodeId only tick the Running nodes on the path to their target;
+// events targeting uuid.Nil are broadcast to the whole tree.
 func (bt *Tree) Update(ctx context.Context, evt core.Event) core.ResultDetails {
-	result := core.Update(ctx, bt.root, evt)
+	ctx = core.WithPanicPolicy(ctx, bt.panics)
+	ctx = core.WithAsyncErrorPolicy(ctx, bt.asyncErrs)
+	ctx = core.WithStructureListener(ctx, bt.index.invalidate)
+	defer bt.publish()
+	if bt.blackboard != nil {
+		ctx = blackboard.WithContext(ctx, bt.blackboard)
+	}
+	result := core.Update(bt.route(ctx, evt), bt.root, evt)
 
 	status := result.Status()
 	if status == core.StatusError {
@@ -59,24 +145,30 @@ func (bt *Tree) Update(ctx context.Context, evt core.Event) core.ResultDetails {
 	}
 
 	handleRunningResultDetails := func(running core.InitRunningResultDetails) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		defer context.AfterFunc(bt.runningCtx, cancel)()
+
 		err := running.RunningFn(ctx, func(evt core.Event) error {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case bt.events <- evt:
-				return nil
-			}
+			return bt.events.Push(ctx, evt)
 		})
-		// If we aren't shutting down, feed the error back through the event loop.
+		// If we aren't shutting down, feed the error back through the event
+		// loop to the node that started the function, so its parents can
+		// handle it. Only unattributed errors go to the tree directly.
 		if err != nil && !errors.Is(err, context.Canceled) {
 			internal.Logger.Error("Error in running function", "err", err)
 
-			select {
-			case <-ctx.Done():
-				return
-			case bt.events <- core.ErrorEvent{Err: err}:
+			if bt.recordShutdownError(err) {
 				return
 			}
+
+			var evt core.Event = core.ErrorEvent{Err: err}
+			if running.NodeId != uuid.Nil {
+				evt = core.RunningErrorEvent{NodeId: running.NodeId, Generation: running.Generation, Err: err}
+			}
+			if err := bt.events.Push(ctx, evt); err != nil && !errors.Is(err, context.Canceled) {
+				internal.Logger.Error("Could not queue error from running function", "err", err)
+			}
 		}
 	}
 
@@ -88,10 +180,10 @@ func (bt *Tree) Update(ctx context.Context, evt core.Event) core.ResultDetails {
 		// whatever
 	case core.StatusRunning:
 		if running, ok := result.(core.InitRunningResultDetails); ok {
-			go handleRunningResultDetails(running)
+			bt.running.Go(func() { handleRunningResultDetails(running) })
 		} else if runnings, ok := result.(core.InitRunningResultsDetailsCollection); ok {
 			for _, running := range runnings.Results {
-				go handleRunningResultDetails(running)
+				bt.running.Go(func() { handleRunningResultDetails(running) })
 			}
 		}
 	default:
@@ -106,45 +198,159 @@ func (bt *Tree) Update(ctx context.Context, evt core.Event) core.ResultDetails {
 }
 
 // EventLoop runs the behavior tree, starting with the provided initial event,
-// continuously until either the context is canceled or an error occurs.
+// continuously until either the context is canceled, the tree is shut down
+// or an error occurs. What happens when the root completes is decided by the
+// tree's CompletionPolicy, see WithCompletionPolicy.
+//
+// If the tree was created with WithTickInterval or WithAdaptiveTickInterval,
+// the root is also ticked with a core.TickEvent whenever the clock fires.
 func (bt *Tree) EventLoop(ctx context.Context, evt core.Event) error {
+	return bt.eventLoop(ctx, evt, bt.completion)
+}
+
+func (bt *Tree) eventLoop(ctx context.Context, initial core.Event, policy CompletionPolicy) error {
+	if err := bt.startLoop(); err != nil {
+		return err
+	}
+	defer bt.endLoop()
+
 	// Put the first event on the queue.
-	bt.events <- evt
+	if err := bt.events.Push(ctx, initial); err != nil {
+		return err
+	}
+
+	// A nil channel never fires, so without a clock only events are processed.
+	var ticks <-chan time.Time
+	var timer *time.Timer
+	if bt.clock != nil {
+		bt.clock.start(time.Now())
+		timer = time.NewTimer(bt.clock.interval)
+		defer timer.Stop()
+		ticks = timer.C
+	}
+
+	// Restarts are fed back through the loop rather than the queue, which
+	// the loop itself must never block on. While one is pending, the
+	// closed restart channel makes the select below pick it up.
+	pendingRestart := false
+	restart := make(chan struct{})
+	close(restart)
+	idle := false
+
+	// handle processes evt and applies the completion policy, reporting
+	// whether the loop should exit.
+	handle := func(evt core.Event) (bool, error) {
+		if reset, ok := evt.(resetEvent); ok {
+			idle = false
+			evt = reset.evt
+		} else if _, ok := evt.(core.ErrorEvent); idle && !ok {
+			internal.Logger.Debug("Tree is idle, ignoring event", "event", evt)
+			return false, nil
+		}
+
+		if err := bt.process(ctx, evt); err != nil {
+			return true, err
+		}
+
+		if s := bt.root.Result().Status(); s != core.StatusSuccess && s != core.StatusFailure {
+			return false, nil
+		}
+
+		switch policy {
+		case CompletionExit:
+			return true, nil
+		case CompletionRestart:
+			pendingRestart = true
+		case CompletionIdle:
+			idle = true
+		}
+		return false, nil
+	}
+
+	// Whether anything happened since the previous clock tick.
+	active := false
 
 	for {
+		var (
+			exit bool
+			err  error
+		)
+
+		var restarts <-chan struct{}
+		if pendingRestart {
+			restarts = restart
+		}
+
 		select {
 		case <-ctx.Done():
 			return nil
-		case evt := <-bt.events:
-			if errEvt, ok := evt.(core.ErrorEvent); ok {
-				return errEvt.Err
+		case <-bt.stop:
+			return nil
+		case req := <-bt.requests:
+			req.fn()
+			close(req.done)
+		case <-restarts:
+			pendingRestart = false
+			internal.Logger.Info("Restarting with Event", "event", initial)
+			exit, err = handle(initial)
+		case <-bt.events.Ready():
+			evt, ok := bt.events.Pop()
+			if !ok {
+				continue
 			}
+			active = true
 			internal.Logger.Info("Updating with Event", "event", evt)
-			result := bt.Update(ctx, evt)
-			if result.Status() == core.StatusError {
-				if details, ok := result.(core.ErrorResultDetails); ok {
-					return details.Err
-				} else {
-					// we should not be able to get here because currently Update ensures that an error status always
-					// has ErrorResultDetails, but if that ever changes and we get here, we should still emit an error
-					return fmt.Errorf("BT Update returned an error with no details %v", details)
-				}
-			}
+			exit, err = handle(evt)
+		case now := <-ticks:
+			before := bt.root.Result().Status()
+			tick := bt.clock.next(now)
+			internal.Logger.Debug("Updating with Tick", "tick", tick.Tick)
+			exit, err = handle(tick)
+			active = active || bt.root.Result().Status() != before
+			timer.Reset(bt.clock.adapt(active))
+			active = false
+		}
+
+		if exit {
+			return err
 		}
 	}
 }
 
+// process handles a single event taken off the queue or produced by the
+// clock, returning the error that should stop the event loop, if any.
+func (bt *Tree) process(ctx context.Context, evt core.Event) error {
+	if errEvt, ok := evt.(core.ErrorEvent); ok {
+		return errEvt.Err
+	}
+	result := bt.Update(ctx, evt)
+	if result.Status() == core.StatusError {
+		if details, ok := result.(core.ErrorResultDetails); ok {
+			return details.Err
+		} else {
+			// we should not be able to get here because currently Update ensures that an error status always
+			// has ErrorResultDetails, but if that ever changes and we get here, we should still emit an error
+			return fmt.Errorf("BT Update returned an error with no details %v", details)
+		}
+	}
+	return nil
+}
+
+// Halt preempts the whole tree, halting every Running node from the
+// root down so that the next Update starts from scratch.
+func (bt *Tree) Halt(ctx context.Context) error {
+	defer bt.publish()
+	return core.Halt(ctx, bt.root)
+}
+
 // String creates a string representation of the behavior tree
 // by traversing it and writing lexical elements to a string
 func (bt *Tree) String() string {
 	return util.NodeToString(bt.root)
 }
 
+// Enqueue adds an event to the tree's event queue, to be processed by
+// EventLoop.
 func (bt *Tree) Enqueue(ctx context.Context, evt core.Event) error {
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case bt.events <- evt:
-		return nil
-	}
+	return bt.events.Push(ctx, evt)
 }
@@ -2,13 +2,24 @@ package greenstalk
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"runtime"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/jbcpollak/greenstalk/v2/common/condition"
+	"github.com/jbcpollak/greenstalk/v2/common/state"
 	"github.com/jbcpollak/greenstalk/v2/core"
 	"github.com/jbcpollak/greenstalk/v2/internal"
+	"github.com/jbcpollak/greenstalk/v2/queue"
 	"github.com/jbcpollak/greenstalk/v2/util"
 
 	// Use dot imports to make a tree definition look nice.
@@ -166,7 +177,8 @@ func TestEventLoop(t *testing.T) {
 
 type errorAsyncNode struct {
 	core.Leaf[core.BaseParams]
-	wg *sync.WaitGroup
+	wg   *sync.WaitGroup
+	left atomic.Bool
 }
 
 func (a *errorAsyncNode) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
@@ -182,7 +194,8 @@ func (a *errorAsyncNode) Tick(ctx context.Context, evt core.Event) core.ResultDe
 }
 
 func (a *errorAsyncNode) Leave(context.Context) error {
-	panic("Should never leave during tests")
+	a.left.Store(true)
+	return nil
 }
 
 var _ core.Node = (*errorAsyncNode)(nil)
@@ -218,15 +231,1407 @@ func TestAsyncErrorInTree(t *testing.T) {
 	treeWG.Go(func() {
 		evt := core.DefaultEvent{}
 		err := tree.EventLoop(ctx, evt)
-		if err == nil || err.Error() != "Expected error during tests" {
-			t.Errorf("Tree should have returned an expected error")
+		var nodeErr *core.NodeError
+		if !errors.As(err, &nodeErr) || nodeErr.Err.Error() != "Expected error during tests" {
+			t.Errorf("Tree should have returned an expected error, got %v", err)
+		} else if nodeErr.NodeId != errorFuncNode.Id() {
+			t.Errorf("Expected error to be attributed to %v, got %v", errorFuncNode.Id(), nodeErr.NodeId)
+		} else if nodeErr.Event != evt {
+			t.Errorf("Expected error to carry the activating event, got %v", nodeErr.Event)
 		}
 	})
 
 	nodeWG.Wait()
 	treeWG.Wait()
 
+	if !errorFuncNode.left.Load() {
+		t.Errorf("Expected the errored node to be left")
+	}
+
 	cancel()
 
 	t.Logf("Tree terminated cleanly")
 }
+
+// tickCounter stays Running and counts how often it is ticked.
+type tickCounter struct {
+	core.Leaf[core.BaseParams]
+	ticks int
+}
+
+func (a *tickCounter) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
+	return core.RunningResult()
+}
+
+func (a *tickCounter) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
+	a.ticks++
+	return core.RunningResult()
+}
+
+func (a *tickCounter) Leave(context.Context) error {
+	return nil
+}
+
+var _ core.Node = (*tickCounter)(nil)
+
+func makeTickCounter(name string) *tickCounter {
+	return &tickCounter{Leaf: core.NewLeaf(core.BaseParams(name))}
+}
+
+func TestTargetedEventDispatch(t *testing.T) {
+	ctx := t.Context()
+
+	first := makeTickCounter("First")
+	second := makeTickCounter("Second")
+	third := makeTickCounter("Third")
+
+	tree, err := NewBehaviorTree(
+		Parallel(0, 0,
+			Sequence(first),
+			Inverter(second),
+			third,
+		),
+	)
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	tree.Update(ctx, core.DefaultEvent{})
+
+	// Only the target, and the running nodes above it, are ticked.
+	tree.Update(ctx, core.TargetNodeEvent(second.Id()))
+	if first.ticks != 0 || second.ticks != 1 || third.ticks != 0 {
+		t.Errorf("Expected only the target to be ticked, got %d %d %d", first.ticks, second.ticks, third.ticks)
+	}
+
+	// Broadcast events still reach everything.
+	tree.Update(ctx, core.DefaultEvent{})
+	if first.ticks != 1 || second.ticks != 2 || third.ticks != 1 {
+		t.Errorf("Expected every node to be ticked, got %d %d %d", first.ticks, second.ticks, third.ticks)
+	}
+
+	// Unknown targets fall back to a broadcast.
+	tree.Update(ctx, core.TargetNodeEvent(uuid.New()))
+	if first.ticks != 2 || second.ticks != 3 || third.ticks != 2 {
+		t.Errorf("Expected every node to be ticked, got %d %d %d", first.ticks, second.ticks, third.ticks)
+	}
+}
+
+// tickRecorder stays Running and forwards every TickEvent it receives.
+type tickRecorder struct {
+	core.Leaf[core.BaseParams]
+	ticks chan core.TickEvent
+}
+
+func (a *tickRecorder) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
+	return a.Tick(ctx, evt)
+}
+
+func (a *tickRecorder) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
+	if tick, ok := evt.(core.TickEvent); ok {
+		a.ticks <- tick
+	}
+	return core.RunningResult()
+}
+
+func (a *tickRecorder) Leave(context.Context) error {
+	return nil
+}
+
+var _ core.Node = (*tickRecorder)(nil)
+
+func TestTickInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	recorder := &tickRecorder{
+		Leaf:  core.NewLeaf(core.BaseParams("Recorder")),
+		ticks: make(chan core.TickEvent, 10),
+	}
+
+	tree, err := NewBehaviorTree(recorder, WithTickInterval(10*time.Millisecond))
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Go(func() {
+		err := tree.EventLoop(ctx, core.DefaultEvent{})
+		if err != nil {
+			t.Errorf("Unexpectedly got %v", err)
+		}
+	})
+
+	var last core.TickEvent
+	for i := uint64(1); i <= 3; i++ {
+		select {
+		case tick := <-recorder.ticks:
+			if tick.Tick != i {
+				t.Errorf("Expected tick %d, got %d", i, tick.Tick)
+			}
+			if tick.Delta <= 0 || (i > 1 && tick.Time.Sub(last.Time) != tick.Delta) {
+				t.Errorf("Unexpected delta %v for tick %d", tick.Delta, tick.Tick)
+			}
+			last = tick
+		case <-time.After(100 * time.Millisecond):
+			t.Fatalf("Timed out waiting for tick %d", i)
+		}
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+func TestTickIntervalDrivesDelayer(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	sigChan := make(chan bool)
+	root := Delayer(
+		DelayerParams{BaseParams: "Delayer", Delay: 30 * time.Millisecond},
+		Signaller(SignallerParams[bool]{BaseParams: "Signaller", Channel: sigChan, Signal: true}),
+	)
+
+	tree, err := NewBehaviorTree(root, WithAdaptiveTickInterval(5*time.Millisecond, 20*time.Millisecond))
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Go(func() {
+		err := tree.EventLoop(ctx, core.DefaultEvent{})
+		if err != nil {
+			t.Errorf("Unexpectedly got %v", err)
+		}
+	})
+
+	signal, err := internal.WaitForSignalOrTimeout(sigChan, 200*time.Millisecond)
+	if err != nil || !signal {
+		t.Errorf("Delayer did not complete on clock ticks: %v", err)
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+func TestInvalidTickInterval(t *testing.T) {
+	cases := []struct {
+		name     string
+		opt      TreeOption
+		expected string
+	}{
+		{"zero interval", WithTickInterval(0), "interval must be positive"},
+		{"negative interval", WithTickInterval(-time.Second), "interval must be positive"},
+		{"zero minimum", WithAdaptiveTickInterval(0, time.Second), "minimum interval must be positive"},
+		{"maximum below minimum", WithAdaptiveTickInterval(time.Second, time.Millisecond), "smaller than minimum"},
+	}
+	for _, c := range cases {
+		_, err := NewBehaviorTree(Succeed(SucceedParams{}), c.opt)
+		if err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", c.name, c.expected, err)
+		}
+	}
+}
+
+// floodingNode enqueues events until it can't anymore.
+type floodingNode struct {
+	core.Leaf[core.BaseParams]
+	pushed chan struct{}
+}
+
+func (a *floodingNode) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
+	return core.InitRunningResult(func(ctx context.Context, enqueue core.EnqueueFn) error {
+		for {
+			if err := enqueue(core.DefaultEvent{}); err != nil {
+				return err
+			}
+			select {
+			case a.pushed <- struct{}{}:
+			default:
+			}
+		}
+	})
+}
+
+func (a *floodingNode) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
+	return core.RunningResult()
+}
+
+func (a *floodingNode) Leave(context.Context) error {
+	return nil
+}
+
+func TestShutdownWithFullQueue(t *testing.T) {
+	flooding := &floodingNode{Leaf: core.NewLeaf(core.BaseParams("Flooding")), pushed: make(chan struct{})}
+	tree, err := NewBehaviorTree(flooding, WithEventQueue(queue.NewBounded(1, queue.Block)))
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	tree.Update(t.Context(), core.DefaultEvent{})
+	<-flooding.pushed
+	// The next push blocks, since nothing pops the queue.
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+	if err := tree.Shutdown(ctx); err != nil {
+		t.Errorf("Expected the blocked RunningFn to stop, got %v", err)
+	}
+}
+
+var errCleanup = errors.New("Expected cleanup error during tests")
+
+// cleanupAsyncNode runs until cancelled, then fails to clean up.
+type cleanupAsyncNode struct {
+	core.Leaf[core.BaseParams]
+	started chan struct{}
+}
+
+func (a *cleanupAsyncNode) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
+	return core.InitRunningResult(func(ctx context.Context, enqueue core.EnqueueFn) error {
+		close(a.started)
+		<-ctx.Done()
+		return errCleanup
+	})
+}
+
+func (a *cleanupAsyncNode) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
+	return core.RunningResult()
+}
+
+func (a *cleanupAsyncNode) Leave(context.Context) error {
+	return nil
+}
+
+var _ core.Node = (*cleanupAsyncNode)(nil)
+
+func TestShutdown(t *testing.T) {
+	asyncNode := &cleanupAsyncNode{
+		Leaf:    core.NewLeaf(core.BaseParams("Cleanup")),
+		started: make(chan struct{}),
+	}
+
+	closeCalled := false
+	root := With(func(context.Context) (func(context.Context) error, error) {
+		return func(context.Context) error {
+			closeCalled = true
+			return nil
+		}, nil
+	}, asyncNode)
+
+	tree, err := NewBehaviorTree(root)
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	loopWG := sync.WaitGroup{}
+	loopWG.Go(func() {
+		err := tree.EventLoop(t.Context(), core.DefaultEvent{})
+		if err != nil {
+			t.Errorf("Unexpectedly got %v", err)
+		}
+	})
+
+	<-asyncNode.started
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+	err = tree.Shutdown(ctx)
+	if !errors.Is(err, errCleanup) {
+		t.Errorf("Expected the running function's error, got %v", err)
+	}
+
+	loopWG.Wait()
+
+	if !closeCalled {
+		t.Errorf("Expected With close function to run on shutdown")
+	}
+	if status := root.Result().Status(); status != core.StatusInvalid {
+		t.Errorf("Expected root to be halted, got %v", status)
+	}
+
+	if err := tree.EventLoop(t.Context(), core.DefaultEvent{}); !errors.Is(err, ErrTreeShutdown) {
+		t.Errorf("Expected ErrTreeShutdown, got %v", err)
+	}
+	if err := tree.Close(); !errors.Is(err, ErrTreeShutdown) {
+		t.Errorf("Expected ErrTreeShutdown, got %v", err)
+	}
+}
+
+func TestRunToCompletion(t *testing.T) {
+	asyncSucceed := AsyncFunctionAction(AsyncFunctionActionParams{
+		BaseParams: "AsyncSucceed",
+		Func: func(ctx context.Context) core.ResultDetails {
+			return core.SuccessResult()
+		},
+	})
+
+	tree, err := NewBehaviorTree(Sequence(asyncSucceed, Succeed(SucceedParams{})))
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	result, err := tree.RunToCompletion(t.Context(), core.DefaultEvent{})
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+	if result.Status() != core.StatusSuccess {
+		t.Errorf("Expected success, got %v", result.Status())
+	}
+
+	tree, err = NewBehaviorTree(Fail(FailParams{}))
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	result, err = tree.RunToCompletion(t.Context(), core.DefaultEvent{})
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+	if result.Status() != core.StatusFailure {
+		t.Errorf("Expected failure, got %v", result.Status())
+	}
+}
+
+func TestCompletionRestart(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	runs := 0
+	root := FunctionAction(FunctionActionParams{
+		BaseParams: "Run",
+		Func: func() core.ResultDetails {
+			runs++
+			if runs == 3 {
+				cancel()
+			}
+			return core.SuccessResult()
+		},
+	})
+
+	tree, err := NewBehaviorTree(root, WithCompletionPolicy(CompletionRestart))
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	if err := tree.EventLoop(ctx, core.DefaultEvent{}); err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+	if runs < 3 {
+		t.Errorf("Expected the root to be restarted, ran %d times", runs)
+	}
+}
+
+func TestCompletionRestartWithQueuedEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	started := make(chan struct{}, 1)
+	root := FunctionAction(FunctionActionParams{
+		BaseParams: "Run",
+		Func: func() core.ResultDetails {
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			return core.SuccessResult()
+		},
+	})
+	tree, err := NewBehaviorTree(root, WithCompletionPolicy(CompletionRestart))
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	// Queued events complete the root while a restart is pending.
+	for range 50 {
+		if err := tree.Enqueue(ctx, core.DefaultEvent{}); err != nil {
+			t.Fatalf("Unexpectedly got %v", err)
+		}
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- tree.EventLoop(ctx, core.DefaultEvent{})
+	}()
+	<-started
+
+	doCtx, doCancel := context.WithTimeout(ctx, time.Second)
+	defer doCancel()
+	if err := tree.Do(doCtx, func() {}); err != nil {
+		t.Errorf("Expected the loop to serve Do, got %v", err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Unexpectedly got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected the loop to exit when cancelled")
+	}
+}
+
+func TestCompletionIdle(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	runs := make(chan int, 10)
+	count := 0
+	root := FunctionAction(FunctionActionParams{
+		BaseParams: "Run",
+		Func: func() core.ResultDetails {
+			count++
+			runs <- count
+			return core.SuccessResult()
+		},
+	})
+
+	tree, err := NewBehaviorTree(root, WithCompletionPolicy(CompletionIdle))
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Go(func() {
+		err := tree.EventLoop(ctx, core.DefaultEvent{})
+		if err != nil {
+			t.Errorf("Unexpectedly got %v", err)
+		}
+	})
+
+	if n := <-runs; n != 1 {
+		t.Errorf("Expected first run, got %d", n)
+	}
+
+	// Stray events are ignored while idle.
+	if err := tree.Enqueue(ctx, core.DefaultEvent{}); err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+	if err := tree.Reset(ctx, core.DefaultEvent{}); err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	if n := <-runs; n != 2 {
+		t.Errorf("Expected second run after reset, got %d", n)
+	}
+
+	cancel()
+	wg.Wait()
+
+	if len(runs) != 0 {
+		t.Errorf("Expected no further runs while idle, got %d", <-runs)
+	}
+}
+
+func makePanicky() core.Node {
+	return FunctionAction(FunctionActionParams{
+		BaseParams: "Panicky",
+		Func: func() core.ResultDetails {
+			panic("Expected panic during tests")
+		},
+	})
+}
+
+func TestPanicErrorsTree(t *testing.T) {
+	panicky := makePanicky()
+	tree, err := NewBehaviorTree(Sequence(panicky))
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	result := tree.Update(t.Context(), core.DefaultEvent{})
+	details, ok := result.(core.ErrorResultDetails)
+	if !ok {
+		t.Fatalf("Expected an error result, got %v", result)
+	}
+
+	var panicErr *core.PanicError
+	if !errors.As(details.Err, &panicErr) {
+		t.Fatalf("Expected a PanicError, got %v", details.Err)
+	}
+	if panicErr.NodeId != panicky.Id() || panicErr.FullName != "Sequence.Panicky" {
+		t.Errorf("Panic attributed to the wrong node: %v", panicErr)
+	}
+	if len(panicErr.Stack) == 0 {
+		t.Errorf("Expected a stack trace")
+	}
+}
+
+func TestPanicFailsNode(t *testing.T) {
+	tree, err := NewBehaviorTree(
+		Selector(makePanicky(), Succeed(SucceedParams{})),
+		WithPanicPolicy(core.PanicFailNode),
+	)
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	if status := tree.Update(t.Context(), core.DefaultEvent{}).Status(); status != core.StatusSuccess {
+		t.Errorf("Expected the selector to fall back, got %v", status)
+	}
+}
+
+func TestPanicCrashes(t *testing.T) {
+	tree, err := NewBehaviorTree(makePanicky(), WithPanicPolicy(core.PanicCrash))
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected the panic to propagate")
+		}
+	}()
+	tree.Update(t.Context(), core.DefaultEvent{})
+}
+
+func TestPanicInRunningFn(t *testing.T) {
+	asyncPanicky := AsyncFunctionAction(AsyncFunctionActionParams{
+		BaseParams: "AsyncPanicky",
+		Func: func(ctx context.Context) core.ResultDetails {
+			panic("Expected panic during tests")
+		},
+	})
+
+	tree, err := NewBehaviorTree(asyncPanicky)
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	err = tree.EventLoop(t.Context(), core.DefaultEvent{})
+
+	var panicErr *core.PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("Expected a PanicError, got %v", err)
+	}
+	if panicErr.NodeId != asyncPanicky.Id() {
+		t.Errorf("Panic attributed to the wrong node: %v", panicErr)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := Sequence(
+		Succeed(SucceedParams{BaseParams: "First"}),
+		Succeed(SucceedParams{BaseParams: "Second"}),
+	)
+	if _, err := NewBehaviorTree(valid, WithValidation()); err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	reused := Succeed(SucceedParams{BaseParams: "Reused"})
+	invalid := SequenceNamed("Root",
+		reused,
+		reused,
+		Succeed(SucceedParams{}),
+		Succeed(SucceedParams{}),
+		Sequence(nil),
+		Selector(),
+		Parallel(3, 0, Fail(FailParams{})),
+		condition.Switch(nil, Fail(FailParams{})),
+		RepeatUntil(RepeatUntilParams{BaseParams: "NoCondition"}, Fail(FailParams{})),
+	)
+
+	_, err := NewBehaviorTree(invalid, WithValidation())
+	if err == nil {
+		t.Fatalf("Expected validation to fail")
+	}
+
+	for _, problem := range []string{
+		"node Root.SucceedReused is used more than once",
+		"more than one node is named Root.Succeed",
+		"Root.Sequence: child 0 is nil",
+		"Root.Selector: composite has no children",
+		"Root.Parallel: success threshold 3 exceeds child count 1",
+		"Root.Switch: switch function is nil",
+		"Root.NoCondition: invalid params: Until condition is nil",
+	} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("Expected %q to be reported in %v", problem, err)
+		}
+	}
+}
+
+func TestHaltNilChild(t *testing.T) {
+	for _, node := range []core.Node{Inverter(nil), Sequence(nil)} {
+		node.SetResult(core.RunningResult())
+		if err := core.Halt(t.Context(), node); err != nil {
+			t.Errorf("%s: unexpectedly got %v", node.Name(), err)
+		}
+		if status := node.Result().Status(); status != core.StatusInvalid {
+			t.Errorf("%s: expected to be reset to invalid, got %v", node.Name(), status)
+		}
+	}
+}
+
+func TestNodeLookup(t *testing.T) {
+	retry := UntilSuccessNamed("Retry", Succeed(SucceedParams{BaseParams: "Inner"}))
+	root := SequenceNamed("Root",
+		SelectorNamed("Fallback", retry),
+		InverterNamed("Retry", Fail(FailParams{})),
+		SequenceNamed("Deep", SequenceNamed("Deeper", UntilFailureNamed("Retry", Fail(FailParams{})))),
+	)
+
+	tree, err := NewBehaviorTree(root)
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	if node, ok := tree.NodeByID(retry.Id()); !ok || node.FullName() != "Root.Fallback.Retry" {
+		t.Errorf("Expected to find Root.Fallback.Retry by id, got %v", node)
+	}
+	if _, ok := tree.NodeByID(uuid.New()); ok {
+		t.Errorf("Unexpectedly found an unknown id")
+	}
+
+	if node, ok := tree.NodeByPath("Root.Fallback.Retry"); !ok || node.Id() != retry.Id() {
+		t.Errorf("Expected to find Root.Fallback.Retry by path, got %v", node)
+	}
+	if _, ok := tree.NodeByPath("Root.Retry.Missing"); ok {
+		t.Errorf("Unexpectedly found an unknown path")
+	}
+
+	names := func(nodes []core.Walkable) []string {
+		var names []string
+		for _, node := range nodes {
+			names = append(names, node.FullName())
+		}
+		return names
+	}
+
+	for pattern, expected := range map[string][]string{
+		"Root.*.Retry":     {"Root.Fallback.Retry"},
+		"Root.Retry":       {"Root.Retry"},
+		"Root.**.Retry":    {"Root.Fallback.Retry", "Root.Retry", "Root.Deep.Deeper.Retry"},
+		"**.Retry.*":       {"Root.Fallback.Retry.SucceedInner", "Root.Retry.Fail", "Root.Deep.Deeper.Retry.Fail"},
+		"Root.Deep*.**.R*": {"Root.Deep.Deeper.Retry"},
+	} {
+		if found := names(tree.FindNodes(pattern)); !slices.Equal(found, expected) {
+			t.Errorf("Pattern %s: expected %v, got %v", pattern, expected, found)
+		}
+	}
+}
+
+func TestNodeLookupAfterDynamicChild(t *testing.T) {
+	var child core.Node
+	root := DynamicDecoratorNamed("Dynamic", func() (core.Node, error) {
+		child = Succeed(SucceedParams{BaseParams: "Child"})
+		return child, nil
+	})
+
+	tree, err := NewBehaviorTree(root)
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	tree.Update(t.Context(), core.DefaultEvent{})
+	first := child
+	if node, ok := tree.NodeByPath("Dynamic.SucceedChild"); !ok || node.Id() != first.Id() {
+		t.Errorf("Expected to find the dynamic child, got %v", node)
+	}
+
+	tree.Update(t.Context(), core.DefaultEvent{})
+	if _, ok := tree.NodeByID(first.Id()); ok {
+		t.Errorf("Expected the replaced child to be dropped from the index")
+	}
+	if _, ok := tree.NodeByID(child.Id()); !ok {
+		t.Errorf("Expected the new child to be indexed")
+	}
+}
+
+func TestNodeLookupWhileRunning(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	// Every tick replaces the child, invalidating the index.
+	root := DynamicDecoratorNamed("Dynamic", func() (core.Node, error) {
+		return Succeed(SucceedParams{BaseParams: "Child"}), nil
+	})
+	tree, err := NewBehaviorTree(root, WithTickInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Go(func() {
+		if err := tree.EventLoop(ctx, core.DefaultEvent{}); err != nil {
+			t.Errorf("Unexpectedly got %v", err)
+		}
+	})
+
+	deadline := time.Now().Add(50 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		if _, ok := tree.NodeByID(root.Id()); !ok {
+			t.Errorf("Expected to find the root")
+		}
+		tree.NodeByPath("Dynamic.SucceedChild")
+		tree.FindNodes("**")
+		tree.Result()
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+func TestSnapshotWhileRunning(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	root := UntilFailureNamed("Loop",
+		SequenceNamed("Body",
+			AsyncDelayer(
+				AsyncDelayerParams{BaseParams: "Delay", Delay: time.Millisecond},
+				Succeed(SucceedParams{BaseParams: "Step"}),
+			),
+		),
+	)
+
+	tree, err := NewBehaviorTree(root)
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Go(func() {
+		err := tree.EventLoop(ctx, core.DefaultEvent{})
+		if err != nil {
+			t.Errorf("Unexpectedly got %v", err)
+		}
+	})
+
+	for range 20 {
+		snapshot := tree.Snapshot()
+		if len(snapshot) != 4 {
+			t.Fatalf("Expected 4 nodes, got %d", len(snapshot))
+		}
+		if snapshot[2].Path != "Loop.Body.Delay" || snapshot[2].Category != core.CategoryDecorator || snapshot[2].Level != 2 {
+			t.Errorf("Unexpected snapshot of Delay %+v", snapshot[2])
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	var found int
+	err = tree.Do(ctx, func() {
+		found = len(tree.FindNodes("**.SucceedStep"))
+	})
+	if err != nil || found != 1 {
+		t.Errorf("Expected Do to find the step, got %d, %v", found, err)
+	}
+
+	cancel()
+	wg.Wait()
+
+	// Without an event loop, Do and Snapshot run inline.
+	if snapshot := tree.Snapshot(); snapshot[0].Status != core.StatusRunning {
+		t.Errorf("Expected root to still be running, got %v", snapshot[0].Status)
+	}
+}
+
+func TestExplainFailure(t *testing.T) {
+	noTarget := FunctionAction(FunctionActionParams{
+		BaseParams: "FindTarget",
+		Func: func() core.ResultDetails {
+			return core.FailureResultWithReason("no target in range")
+		},
+	})
+	noPath := errors.New("no path")
+	blocked := FunctionAction(FunctionActionParams{
+		BaseParams: "MoveToTarget",
+		Func: func() core.ResultDetails {
+			return core.FailureResultWithError(noPath)
+		},
+	})
+
+	tree, err := NewBehaviorTree(
+		SequenceNamed("Root",
+			SelectorNamed("Attack", noTarget, blocked),
+			Succeed(SucceedParams{}),
+		),
+	)
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	if status := tree.Update(t.Context(), core.DefaultEvent{}).Status(); status != core.StatusFailure {
+		t.Fatalf("Expected failure, got %v", status)
+	}
+
+	steps := tree.Explain()
+	expected := []struct {
+		path   string
+		reason string
+		level  int
+	}{
+		{"Root", "child Attack failed", 0},
+		{"Root.Attack", "all children failed", 1},
+		{"Root.Attack.FindTarget", "no target in range", 2},
+		{"Root.Attack.MoveToTarget", "no path", 2},
+	}
+	if len(steps) != len(expected) {
+		t.Fatalf("Expected %d steps, got %+v", len(expected), steps)
+	}
+	for i, e := range expected {
+		if steps[i].Path != e.path || steps[i].Reason != e.reason || steps[i].Level != e.level || steps[i].Status != core.StatusFailure {
+			t.Errorf("Step %d: expected %+v, got %+v", i, e, steps[i])
+		}
+	}
+	if !errors.Is(steps[3].Err, noPath) {
+		t.Errorf("Expected the leaf's error to be kept, got %v", steps[3].Err)
+	}
+}
+
+func TestNodeErrorAttribution(t *testing.T) {
+	errBroken := errors.New("sensor broken")
+	sense := FunctionAction(FunctionActionParams{
+		BaseParams: "Sense",
+		Func: func() core.ResultDetails {
+			return core.ErrorResult(errBroken)
+		},
+	})
+
+	tree, err := NewBehaviorTree(SequenceNamed("Root", Succeed(SucceedParams{}), sense))
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	evt := core.DefaultEvent{}
+	result := tree.Update(t.Context(), evt)
+	details, ok := result.(core.ErrorResultDetails)
+	if !ok {
+		t.Fatalf("Expected an error result, got %v", result)
+	}
+
+	var nodeErr *core.NodeError
+	if !errors.As(details.Err, &nodeErr) {
+		t.Fatalf("Expected a NodeError, got %v", details.Err)
+	}
+	if nodeErr.NodeId != sense.Id() || nodeErr.FullName != "Root.Sense" || nodeErr.Category != core.CategoryLeaf {
+		t.Errorf("Error attributed to the wrong node: %v", nodeErr)
+	}
+	if nodeErr.Event != evt {
+		t.Errorf("Expected the event being processed, got %v", nodeErr.Event)
+	}
+	if !errors.Is(details.Err, errBroken) {
+		t.Errorf("Expected the original error to be wrapped, got %v", details.Err)
+	}
+	if details.Err.Error() != "leaf Root.Sense: sensor broken" {
+		t.Errorf("Unexpected message: %v", details.Err)
+	}
+}
+
+var errAsync = errors.New("Expected async error during tests")
+
+// failingAsyncNode starts a RunningFn that fails straight away.
+type failingAsyncNode struct {
+	core.Leaf[core.BaseParams]
+}
+
+func (a *failingAsyncNode) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
+	return core.InitRunningResult(func(ctx context.Context, enqueue core.EnqueueFn) error {
+		return errAsync
+	})
+}
+
+func (a *failingAsyncNode) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
+	return core.RunningResult()
+}
+
+func (a *failingAsyncNode) Leave(context.Context) error {
+	return nil
+}
+
+func TestAsyncErrorFailsNode(t *testing.T) {
+	failing := &failingAsyncNode{Leaf: core.NewLeaf(core.BaseParams("Failing"))}
+	tree, err := NewBehaviorTree(
+		Selector(failing, Succeed(SucceedParams{})),
+		WithAsyncErrorPolicy(core.AsyncErrorFailure),
+	)
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	result, err := tree.RunToCompletion(t.Context(), core.DefaultEvent{})
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if result.Status() != core.StatusSuccess {
+		t.Errorf("Expected the selector to fall back, got %v", result)
+	}
+
+	failure, ok := failing.Result().(core.FailureResultDetails)
+	if !ok || !errors.Is(failure.Error(), errAsync) {
+		t.Errorf("Expected the node to fail with the async error, got %v", failing.Result())
+	}
+}
+
+func TestAsyncErrorReachesRoot(t *testing.T) {
+	failing := &failingAsyncNode{Leaf: core.NewLeaf(core.BaseParams("Failing"))}
+	tree, err := NewBehaviorTree(SequenceNamed("Root", failing))
+	if err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+
+	_, err = tree.RunToCompletion(t.Context(), core.DefaultEvent{})
+	var nodeErr *core.NodeError
+	if !errors.As(err, &nodeErr) || !errors.Is(err, errAsync) {
+		t.Fatalf("Expected the async error, got %v", err)
+	}
+	if nodeErr.NodeId != failing.Id() {
+		t.Errorf("Error attributed to the wrong node: %v", nodeErr)
+	}
+	if status := failing.Result().Status(); status != core.StatusError {
+		t.Errorf("Expected the node to be errored, got %v", status)
+	}
+}
+
+func TestCheckpointRestore(t *testing.T) {
+	build := func(firstRuns *int, counts chan uint) core.Node {
+		return SequenceNamed("Root",
+			FunctionAction(FunctionActionParams{
+				BaseParams: "First",
+				Func: func() core.ResultDetails {
+					*firstRuns++
+					return core.SuccessResult()
+				},
+			}),
+			Repeat(RepeatParams{BaseParams: "Repeat", Cycles: 3},
+				Counter(CounterParams{BaseParams: "Counter", Limit: 10, CountChan: counts}),
+			),
+			AsyncDelayer(AsyncDelayerParams{BaseParams: "Wait", Delay: 10 * time.Millisecond},
+				Succeed(SucceedParams{}),
+			),
+		)
+	}
+
+	firstRuns := 0
+	counts := make(chan uint, 10)
+	tree, err := NewBehaviorTree(build(&firstRuns, counts))
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	tree.Update(t.Context(), core.DefaultEvent{})
+
+	cp, err := tree.Checkpoint(t.Context())
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if state := cp.Nodes["0"]; state.Status != core.StatusRunning || state.CurrentChild != 1 {
+		t.Errorf("Unexpected root state %+v", state)
+	}
+
+	data, err := json.Marshal(cp)
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	var decoded Checkpoint
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	restoredRuns := 0
+	restoredCounts := make(chan uint, 10)
+	restored, err := NewBehaviorTree(build(&restoredRuns, restoredCounts))
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if err := restored.Restore(t.Context(), decoded); err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	result, err := restored.RunToCompletion(t.Context(), core.DefaultEvent{})
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if result.Status() != core.StatusSuccess {
+		t.Errorf("Expected success, got %v", result)
+	}
+	if restoredRuns != 0 {
+		t.Errorf("Expected the completed child not to run again, ran %d times", restoredRuns)
+	}
+	close(restoredCounts)
+	var got []uint
+	for count := range restoredCounts {
+		got = append(got, count)
+	}
+	if !slices.Equal(got, []uint{2, 3}) {
+		t.Errorf("Expected the counter and repeat to resume, got counts %v", got)
+	}
+}
+
+func TestRestoreShapeMismatch(t *testing.T) {
+	tree, err := NewBehaviorTree(SequenceNamed("Root", Succeed(SucceedParams{BaseParams: "A"})))
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	cp, err := tree.Checkpoint(t.Context())
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	other, err := NewBehaviorTree(SequenceNamed("Root", Succeed(SucceedParams{BaseParams: "B"})))
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	err = other.Restore(t.Context(), cp)
+	if err == nil || !strings.Contains(err.Error(), "Root.SucceedB (0/0) is Root.SucceedA in the checkpoint") {
+		t.Errorf("Expected the mismatched node to be reported, got %v", err)
+	}
+
+	larger, err := NewBehaviorTree(SequenceNamed("Root",
+		Succeed(SucceedParams{BaseParams: "A"}),
+		Succeed(SucceedParams{BaseParams: "C"}),
+	))
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	err = larger.Restore(t.Context(), cp)
+	if err == nil || !strings.Contains(err.Error(), "Root.SucceedC (0/1) is not in the checkpoint") {
+		t.Errorf("Expected the extra node to be reported, got %v", err)
+	}
+}
+
+func TestCheckpointIdenticalSiblings(t *testing.T) {
+	build := func() core.Node {
+		return Sequence(
+			Succeed(SucceedParams{}),
+			Sequence(Succeed(SucceedParams{}), Succeed(SucceedParams{})),
+			AsyncDelayer(AsyncDelayerParams{Delay: 10 * time.Millisecond}, Succeed(SucceedParams{})),
+		)
+	}
+
+	tree, err := NewBehaviorTree(build())
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	tree.Update(t.Context(), core.DefaultEvent{})
+	cp, err := tree.Checkpoint(t.Context())
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	tree.Close()
+	if got := slices.Sorted(maps.Keys(cp.Nodes)); !slices.Equal(got, []string{"0", "0/0", "0/1", "0/1/0", "0/1/1", "0/2", "0/2/0"}) {
+		t.Errorf("Unexpected paths %v", got)
+	}
+	if state := cp.Nodes["0"]; state.CurrentChild != 2 {
+		t.Errorf("Unexpected root state %+v", state)
+	}
+
+	restored, err := NewBehaviorTree(build())
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if err := restored.Restore(t.Context(), cp); err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+	result, err := restored.RunToCompletion(ctx, core.DefaultEvent{})
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if result.Status() != core.StatusSuccess {
+		t.Errorf("Expected success, got %v", result)
+	}
+}
+
+func TestCheckpointRandomSequence(t *testing.T) {
+	build := func() core.Node {
+		return RandomSequence(
+			Succeed(SucceedParams{}),
+			Succeed(SucceedParams{}),
+			AsyncDelayer(AsyncDelayerParams{Delay: 10 * time.Millisecond}, Succeed(SucceedParams{})),
+		)
+	}
+
+	tree, err := NewBehaviorTree(build())
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	tree.Update(t.Context(), core.DefaultEvent{})
+	cp, err := tree.Checkpoint(t.Context())
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	tree.Close()
+	if status := cp.Nodes["0/2"].Status; status != core.StatusRunning {
+		t.Fatalf("Expected the delay to be running, got %v", status)
+	}
+
+	restored, err := NewBehaviorTree(build())
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if err := restored.Restore(t.Context(), cp); err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	got, err := restored.Checkpoint(t.Context())
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if string(got.Nodes["0"].State) != string(cp.Nodes["0"].State) {
+		t.Errorf("Expected order %s, got %s", cp.Nodes["0"].State, got.Nodes["0"].State)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+	result, err := restored.RunToCompletion(ctx, core.DefaultEvent{})
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if result.Status() != core.StatusSuccess {
+		t.Errorf("Expected success, got %v", result)
+	}
+}
+
+func TestRestoreResumesDelay(t *testing.T) {
+	build := func() core.Node {
+		return SequenceNamed("Root",
+			Succeed(SucceedParams{}),
+			AsyncDelayer(AsyncDelayerParams{BaseParams: "Wait", Delay: 10 * time.Millisecond},
+				Succeed(SucceedParams{}),
+			),
+		)
+	}
+
+	tree, err := NewBehaviorTree(build())
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	tree.Update(t.Context(), core.DefaultEvent{})
+	cp, err := tree.Checkpoint(t.Context())
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if status := cp.Nodes["0/1"].Status; status != core.StatusRunning {
+		t.Fatalf("Expected the delay to be running, got %v", status)
+	}
+	tree.Close()
+
+	restored, err := NewBehaviorTree(build())
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if err := restored.Restore(t.Context(), cp); err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+	result, err := restored.RunToCompletion(ctx, core.DefaultEvent{})
+	if err != nil {
+		t.Fatalf("Expected the delay to resume, got %v", err)
+	}
+	if result.Status() != core.StatusSuccess {
+		t.Errorf("Expected success, got %v", result)
+	}
+}
+
+func TestTreeTemplate(t *testing.T) {
+	counts := make(chan uint, 100)
+	prototype := Counter(CounterParams{BaseParams: "Counter", Limit: 10, CountChan: counts})
+	root := SequenceNamed("Root",
+		Repeat(RepeatParams{BaseParams: "Repeat", Cycles: 2}, prototype),
+		Parallel(0, 0, Succeed(SucceedParams{BaseParams: "A"}), Succeed(SucceedParams{BaseParams: "B"})),
+	)
+	template, err := NewTreeTemplate(root, WithValidation())
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	ids := map[uuid.UUID]bool{}
+	for i := range 3 {
+		tree, err := template.Instantiate()
+		if err != nil {
+			t.Fatalf("Unexpectedly got %v", err)
+		}
+		counter, ok := tree.NodeByPath("Root.Repeat.Counter")
+		if !ok {
+			t.Fatalf("Expected the instance to have the template's paths")
+		}
+		if ids[counter.Id()] || counter.Id() == prototype.Id() {
+			t.Errorf("Expected every instance to have its own ids")
+		}
+		ids[counter.Id()] = true
+
+		result, err := tree.RunToCompletion(t.Context(), core.DefaultEvent{})
+		if err != nil {
+			t.Fatalf("Unexpectedly got %v", err)
+		}
+		if result.Status() != core.StatusSuccess {
+			t.Errorf("Instance %d: expected success, got %v", i, result)
+		}
+		if first, second := <-counts, <-counts; first != 1 || second != 2 {
+			t.Errorf("Instance %d: expected its own counter, got %d and %d", i, first, second)
+		}
+	}
+
+	if status := root.Result().Status(); status != core.StatusInvalid {
+		t.Errorf("Expected the template to be left untouched, got %v", status)
+	}
+}
+
+func TestTreeTemplateKeepsCopy(t *testing.T) {
+	root := SequenceNamed("Root",
+		AsyncDelayer(AsyncDelayerParams{BaseParams: "Wait", Delay: time.Hour}, Succeed(SucceedParams{})),
+	)
+	template, err := NewTreeTemplate(root)
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	// Running the tree the template was made from must not affect it.
+	original, err := NewBehaviorTree(root)
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	defer original.Close()
+	if result := original.Update(t.Context(), core.DefaultEvent{}); result.Status() != core.StatusRunning {
+		t.Fatalf("Expected the original to be running, got %v", result)
+	}
+
+	tree, err := template.Instantiate()
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if status := tree.Result().Status(); status != core.StatusInvalid {
+		t.Errorf("Expected a fresh instance, got %v", status)
+	}
+	if node, ok := tree.NodeByPath("Root"); !ok || node.Id() == root.Id() {
+		t.Errorf("Expected the instance not to share the original's nodes")
+	}
+}
+
+type tableParams struct {
+	core.BaseParams
+
+	Table [64 << 10]byte
+}
+
+// tableNode is a leaf with large Params.
+type tableNode struct {
+	core.Leaf[tableParams]
+}
+
+func (n *tableNode) Activate(context.Context, core.Event) core.ResultDetails {
+	return core.SuccessResult()
+}
+
+func (n *tableNode) Tick(context.Context, core.Event) core.ResultDetails {
+	return core.SuccessResult()
+}
+
+func (n *tableNode) Leave(context.Context) error {
+	return nil
+}
+
+func TestTreeTemplateSharesParams(t *testing.T) {
+	root := SequenceNamed("Root",
+		&tableNode{Leaf: core.NewLeaf(tableParams{BaseParams: "A"})},
+		&tableNode{Leaf: core.NewLeaf(tableParams{BaseParams: "B"})},
+	)
+	template, err := NewTreeTemplate(root)
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	var nodes []*tableNode
+	for range 2 {
+		tree, err := template.Instantiate()
+		if err != nil {
+			t.Fatalf("Unexpectedly got %v", err)
+		}
+		node, _ := tree.NodeByPath("Root.A")
+		nodes = append(nodes, node.(*tableNode))
+	}
+	if nodes[0] == nodes[1] || &nodes[0].Params != &nodes[1].Params {
+		t.Errorf("Expected instances to have their own nodes sharing their Params")
+	}
+
+	// Further instances only allocate the runtime state of their nodes.
+	const instances = 10
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for range instances {
+		if _, err := template.Instantiate(); err != nil {
+			t.Fatalf("Unexpectedly got %v", err)
+		}
+	}
+	runtime.ReadMemStats(&after)
+	allocated := (after.TotalAlloc - before.TotalAlloc) / instances
+	t.Logf("%d bytes per instance", allocated)
+	if allocated >= 64<<10 {
+		t.Errorf("Expected instances not to copy Params, allocated %d bytes each", allocated)
+	}
+}
+
+type watchedEvent struct {
+	core.DefaultEvent
+	value int
+}
+
+// nextWatched returns the value of the next watchedEvent pushed to q
+// within timeout.
+func nextWatched(q queue.EventQueue, timeout time.Duration) (int, bool) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if evt, ok := q.Pop(); ok {
+			return evt.(watchedEvent).value, true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return 0, false
+}
+
+func TestWatchDistinct(t *testing.T) {
+	q := queue.NewFIFO()
+	tree, err := NewBehaviorTree(Succeed(SucceedParams{}), WithEventQueue(q))
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	provider := &state.StateProvider[int]{}
+	Watch(t.Context(), tree, provider, func(v int) core.Event {
+		return watchedEvent{value: v}
+	}, WatchDistinct[int]())
+
+	provider.Set(1)
+	if v, ok := nextWatched(q, time.Second); !ok || v != 1 {
+		t.Fatalf("Expected an event for 1, got %v, %v", v, ok)
+	}
+	provider.Set(1)
+	if v, ok := nextWatched(q, 50*time.Millisecond); ok {
+		t.Errorf("Expected the unchanged value to be skipped, got %v", v)
+	}
+	provider.Set(2)
+	if v, ok := nextWatched(q, time.Second); !ok || v != 2 {
+		t.Errorf("Expected an event for 2, got %v, %v", v, ok)
+	}
+
+	if err := tree.Shutdown(t.Context()); err != nil {
+		t.Errorf("Unexpectedly got %v", err)
+	}
+}
+
+func TestWatchDebounce(t *testing.T) {
+	q := queue.NewFIFO()
+	tree, err := NewBehaviorTree(Succeed(SucceedParams{}), WithEventQueue(q))
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	provider := &state.StateProvider[int]{}
+	stop := Watch(t.Context(), tree, provider, func(v int) core.Event {
+		return watchedEvent{value: v}
+	}, WatchDebounce[int](20*time.Millisecond))
+	defer stop()
+
+	for i := range 5 {
+		provider.Set(i)
+	}
+	if v, ok := nextWatched(q, time.Second); !ok || v != 4 {
+		t.Fatalf("Expected a single event for the last value, got %v, %v", v, ok)
+	}
+	if v, ok := nextWatched(q, 50*time.Millisecond); ok {
+		t.Errorf("Expected no more events, got %v", v)
+	}
+}
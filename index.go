@@ -0,0 +1,148 @@
+package greenstalk
+
+import (
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+// nodeIndex locates the nodes of a tree. It is built when the tree is
+// created, and rebuilt by the goroutine driving the tree after any update
+// that changes the structure of the tree, e.g. when a DynamicDecorator
+// creates a new child. Lookups only read the index, never the nodes, so
+// they are safe from any goroutine.
+type nodeIndex struct {
+	mu sync.RWMutex
+	// nodes lists every node in depth-first order, and names their
+	// FullNames when the index was built.
+	nodes []core.Node
+	names []string
+	byId  map[uuid.UUID]core.Node
+	// paths maps every node id to the ids of its ancestors, root first,
+	// followed by the node itself. Used to route targeted events.
+	paths map[uuid.UUID][]uuid.UUID
+	stale bool
+}
+
+// build indexes the tree under root. It walks the tree, so it must only
+// be called by the goroutine driving the tree.
+func (idx *nodeIndex) build(root core.Node) {
+	var (
+		nodes []core.Node
+		names []string
+		stack []uuid.UUID
+	)
+	byId := map[uuid.UUID]core.Node{}
+	paths := map[uuid.UUID][]uuid.UUID{}
+	core.WalkNodes(root, func(node core.Node, level int) {
+		stack = append(stack[:level], node.Id())
+		nodes = append(nodes, node)
+		names = append(names, node.FullName())
+		byId[node.Id()] = node
+		paths[node.Id()] = slices.Clone(stack)
+	})
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.nodes, idx.names, idx.byId, idx.paths = nodes, names, byId, paths
+	idx.stale = false
+}
+
+// refresh rebuilds the index if the structure of the tree changed. Like
+// build, it must only be called by the goroutine driving the tree.
+func (idx *nodeIndex) refresh(root core.Node) {
+	idx.mu.RLock()
+	stale := idx.stale
+	idx.mu.RUnlock()
+	if stale {
+		idx.build(root)
+	}
+}
+
+func (idx *nodeIndex) invalidate() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.stale = true
+}
+
+// path returns the ids leading from the root to the node with the given
+// id.
+func (idx *nodeIndex) path(id uuid.UUID) ([]uuid.UUID, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	path, ok := idx.paths[id]
+	return path, ok
+}
+
+// NodeByID returns the node with the given id. It is safe to call while
+// EventLoop is running, but the node must only be inspected through Do.
+func (bt *Tree) NodeByID(id uuid.UUID) (core.Walkable, bool) {
+	bt.index.mu.RLock()
+	defer bt.index.mu.RUnlock()
+	node, ok := bt.index.byId[id]
+	return node, ok
+}
+
+// NodeByPath returns the node whose FullName is path, such as
+// "Sequence.First.First Counter". If several nodes share that name,
+// the first one in depth-first order is returned. It is safe to call
+// while EventLoop is running, but the node must only be inspected
+// through Do.
+func (bt *Tree) NodeByPath(path string) (core.Walkable, bool) {
+	bt.index.mu.RLock()
+	defer bt.index.mu.RUnlock()
+	for i, name := range bt.index.names {
+		if name == path {
+			return bt.index.nodes[i], true
+		}
+	}
+	return nil, false
+}
+
+// FindNodes returns the nodes whose FullName matches pattern, in
+// depth-first order. The pattern is matched segment by segment, segments
+// being separated by core.NAME_PREFIX_SEPARATOR: a "**" segment matches
+// any number of segments, including none, and any other segment is
+// matched against a single name with path.Match, so "*" matches any name
+// and "Retry*" any name starting with "Retry". For example "Root.*.Retry"
+// matches every node named Retry that is a grandchild of Root. It is safe
+// to call while EventLoop is running, but the nodes must only be inspected
+// through Do.
+func (bt *Tree) FindNodes(pattern string) []core.Walkable {
+	patternSegments := strings.Split(pattern, core.NAME_PREFIX_SEPARATOR)
+
+	bt.index.mu.RLock()
+	defer bt.index.mu.RUnlock()
+	var found []core.Walkable
+	for i, name := range bt.index.names {
+		if matchSegments(patternSegments, strings.Split(name, core.NAME_PREFIX_SEPARATOR)) {
+			found = append(found, bt.index.nodes[i])
+		}
+	}
+	return found
+}
+
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
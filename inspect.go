@@ -0,0 +1,83 @@
+package greenstalk
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+// NodeSnapshot is a copy of the state of a node at the time a Snapshot
+// was taken.
+type NodeSnapshot struct {
+	Id       uuid.UUID
+	Path     string
+	Category core.Category
+	Status   core.Status
+	// Level is the depth of the node, the root being at level 0.
+	Level int
+}
+
+// doRequest is a function to run on the event loop goroutine.
+type doRequest struct {
+	fn   func()
+	done chan struct{}
+}
+
+// Do runs fn on the goroutine driving the tree, so that fn can safely
+// inspect or modify nodes while EventLoop is running. If no event loop is
+// running, fn is run directly, and EventLoop cannot start until it
+// returns. Do returns once fn has returned, or with ctx.Err() if ctx
+// expires before fn could be scheduled.
+//
+// fn must not call Do, Snapshot, EventLoop or Shutdown. Trees driven by
+// calling Update directly must be synchronised by the caller.
+func (bt *Tree) Do(ctx context.Context, fn func()) error {
+	for {
+		bt.mu.Lock()
+		loopDone := bt.loopDone
+		if loopDone == nil {
+			defer bt.mu.Unlock()
+			fn()
+			return nil
+		}
+		bt.mu.Unlock()
+
+		req := doRequest{fn: fn, done: make(chan struct{})}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-loopDone:
+			// The loop stopped before picking up the request, try again.
+			continue
+		case bt.requests <- req:
+			<-req.done
+			return nil
+		}
+	}
+}
+
+// Snapshot returns a copy of the id, path, category and status of every
+// node, in depth-first order. It is safe to call while EventLoop is
+// running.
+func (bt *Tree) Snapshot() []NodeSnapshot {
+	var snapshot []NodeSnapshot
+	_ = bt.Do(context.Background(), func() {
+		snapshot = bt.snapshot()
+	})
+	return snapshot
+}
+
+func (bt *Tree) snapshot() []NodeSnapshot {
+	var snapshot []NodeSnapshot
+	core.WalkNodes(bt.root, func(node core.Node, level int) {
+		snapshot = append(snapshot, NodeSnapshot{
+			Id:       node.Id(),
+			Path:     node.FullName(),
+			Category: node.Category(),
+			Status:   node.Result().Status(),
+			Level:    level,
+		})
+	})
+	return snapshot
+}
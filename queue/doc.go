@@ -0,0 +1,3 @@
+// Package queue provides the event queues a behavior tree can be
+// configured with.
+package queue
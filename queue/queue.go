@@ -0,0 +1,349 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jbcpollak/greenstalk/v2/core"
+	"github.com/jbcpollak/greenstalk/v2/internal"
+)
+
+// EventQueue buffers the events waiting to be processed by a tree's
+// event loop. Push may be called from any goroutine, Pop and Ready are
+// only used by the event loop.
+type EventQueue interface {
+	// Push adds an event to the queue. Depending on the queue's overflow
+	// policy it may block until there is room, drop an older event, or
+	// fail with ErrQueueFull.
+	Push(ctx context.Context, evt core.Event) error
+
+	// Pop removes and returns the next event, if there is one.
+	Pop() (core.Event, bool)
+
+	// Ready returns a channel that receives a value whenever events are
+	// available to Pop.
+	Ready() <-chan struct{}
+
+	// Len returns the number of queued events.
+	Len() int
+}
+
+// ErrQueueFull is returned by Push when a bounded queue with the Reject
+// policy is full.
+var ErrQueueFull = errors.New("event queue is full")
+
+// OverflowPolicy decides what a bounded queue does when it is full.
+// core.ControlEvents are never dropped or rejected: when nothing can be
+// dropped to make room for them, they are queued beyond the capacity.
+type OverflowPolicy int
+
+const (
+	// Block makes Push wait until an event has been popped.
+	Block OverflowPolicy = iota
+	// DropOldest discards the oldest event of the lowest priority to make
+	// room for the new one. If every queued event is a core.ControlEvent,
+	// the new event is discarded instead.
+	DropOldest
+	// Reject makes Push fail with ErrQueueFull.
+	Reject
+)
+
+// Priority orders the lanes of a queue. Events in a higher lane are always
+// popped before events in a lower one.
+type Priority int
+
+const (
+	PriorityNormal Priority = iota
+	PriorityHigh
+	PriorityInterrupt
+
+	numPriorities = int(PriorityInterrupt) + 1
+)
+
+// Prioritized can be implemented by events that should skip ahead of
+// normal events in the queue.
+type Prioritized interface {
+	Priority() Priority
+}
+
+// Expiring can be implemented by events that are no longer worth
+// processing after a given time. Expired events are silently dropped.
+// core.ControlEvents never expire.
+type Expiring interface {
+	ExpiresAt() time.Time
+}
+
+// CoalesceFunc reports whether incoming supersedes queued, in which case
+// incoming takes queued's place in the queue instead of being appended.
+// A queued core.ControlEvent is only ever superseded by another one.
+type CoalesceFunc func(queued, incoming core.Event) bool
+
+// Option configures a queue created by New.
+type Option func(*eventQueue)
+
+// WithCapacity bounds the queue to capacity events, applying policy when
+// it is full. A capacity of 0 means unbounded.
+func WithCapacity(capacity int, policy OverflowPolicy) Option {
+	return func(q *eventQueue) {
+		q.capacity = capacity
+		q.policy = policy
+	}
+}
+
+// WithTTL drops events that have been queued for longer than ttl. Events
+// implementing Expiring use their own expiry instead.
+func WithTTL(ttl time.Duration) Option {
+	return func(q *eventQueue) {
+		q.ttl = ttl
+	}
+}
+
+// WithPriorities assigns events to priority lanes with fn. Events
+// implementing Prioritized use their own priority instead.
+func WithPriorities(fn func(core.Event) Priority) Option {
+	return func(q *eventQueue) {
+		q.priority = fn
+	}
+}
+
+// WithCoalescing merges targeted events that are already queued with
+// newer ones. A nil match coalesces targeted events that are equal.
+// Broadcast events are never coalesced.
+func WithCoalescing(match CoalesceFunc) Option {
+	return func(q *eventQueue) {
+		if match == nil {
+			match = Identical
+		}
+		q.coalesce = match
+	}
+}
+
+// Identical is the default CoalesceFunc, matching events that are equal.
+// Events holding values that can't be compared, such as slices in
+// interface fields, never match.
+func Identical(queued, incoming core.Event) (identical bool) {
+	t := reflect.TypeOf(incoming)
+	if t != reflect.TypeOf(queued) || !t.Comparable() {
+		return false
+	}
+	// Comparable types can still hold uncomparable values in interface
+	// fields, and comparing those panics.
+	defer func() {
+		if recover() != nil {
+			identical = false
+		}
+	}()
+	return queued == incoming
+}
+
+// New creates an event queue. Without options it is an unbounded FIFO.
+func New(opts ...Option) EventQueue {
+	q := &eventQueue{
+		ready: make(chan struct{}, 1),
+		space: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+// NewFIFO creates an unbounded first-in first-out queue.
+func NewFIFO() EventQueue {
+	return New()
+}
+
+// NewBounded creates a first-in first-out queue holding at most capacity
+// events.
+func NewBounded(capacity int, policy OverflowPolicy) EventQueue {
+	return New(WithCapacity(capacity, policy))
+}
+
+type item struct {
+	evt     core.Event
+	expires time.Time
+}
+
+type eventQueue struct {
+	capacity int
+	policy   OverflowPolicy
+	ttl      time.Duration
+	priority func(core.Event) Priority
+	coalesce CoalesceFunc
+
+	mu    sync.Mutex
+	lanes [numPriorities][]item
+	count int
+	ready chan struct{}
+	// space is closed, and replaced, every time an event is popped.
+	space chan struct{}
+}
+
+func (q *eventQueue) Push(ctx context.Context, evt core.Event) error {
+	it := item{evt: evt, expires: q.expiry(evt)}
+	lane := q.lane(evt)
+
+	_, control := evt.(core.ControlEvent)
+
+	q.mu.Lock()
+	for {
+		if q.coalesceLocked(lane, it) {
+			q.mu.Unlock()
+			return nil
+		}
+		if q.capacity <= 0 || q.count < q.capacity {
+			break
+		}
+
+		if q.policy == DropOldest && q.dropOldestLocked() {
+			continue
+		}
+		if q.policy != Block {
+			if control {
+				// Control events are queued beyond the capacity.
+				break
+			}
+			q.mu.Unlock()
+			if q.policy == DropOldest {
+				internal.Logger.Debug("Event queue full of control events, dropping event", "event", evt)
+				return nil
+			}
+			return ErrQueueFull
+		}
+
+		space := q.space
+		q.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-space:
+		}
+		q.mu.Lock()
+	}
+
+	q.lanes[lane] = append(q.lanes[lane], it)
+	q.count++
+	q.mu.Unlock()
+
+	q.signal()
+	return nil
+}
+
+func (q *eventQueue) Pop() (core.Event, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	for lane := numPriorities - 1; lane >= 0; lane-- {
+		for len(q.lanes[lane]) > 0 {
+			it := q.lanes[lane][0]
+			q.lanes[lane][0] = item{}
+			q.lanes[lane] = q.lanes[lane][1:]
+			q.removedLocked()
+
+			if !it.expires.IsZero() && now.After(it.expires) {
+				internal.Logger.Debug("Dropping expired event", "event", it.evt)
+				continue
+			}
+
+			if q.count > 0 {
+				q.signal()
+			}
+			return it.evt, true
+		}
+	}
+	return nil, false
+}
+
+func (q *eventQueue) Ready() <-chan struct{} {
+	return q.ready
+}
+
+func (q *eventQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.count
+}
+
+func (q *eventQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (q *eventQueue) expiry(evt core.Event) time.Time {
+	if _, ok := evt.(core.ControlEvent); ok {
+		return time.Time{}
+	}
+	if e, ok := evt.(Expiring); ok {
+		return e.ExpiresAt()
+	}
+	if q.ttl > 0 {
+		return time.Now().Add(q.ttl)
+	}
+	return time.Time{}
+}
+
+func (q *eventQueue) lane(evt core.Event) int {
+	p := PriorityNormal
+	if e, ok := evt.(Prioritized); ok {
+		p = e.Priority()
+	} else if q.priority != nil {
+		p = q.priority(evt)
+	}
+	return min(max(int(p), 0), numPriorities-1)
+}
+
+func (q *eventQueue) coalesceLocked(lane int, it item) bool {
+	if q.coalesce == nil || it.evt.TargetNodeId() == uuid.Nil {
+		return false
+	}
+	// Don't leave the queue locked if the CoalesceFunc panics.
+	defer func() {
+		if r := recover(); r != nil {
+			q.mu.Unlock()
+			panic(r)
+		}
+	}()
+	_, control := it.evt.(core.ControlEvent)
+	for i, queued := range q.lanes[lane] {
+		if _, ok := queued.evt.(core.ControlEvent); ok && !control {
+			// Its replacement could be dropped or expire.
+			continue
+		}
+		if q.coalesce(queued.evt, it.evt) {
+			q.lanes[lane][i] = it
+			return true
+		}
+	}
+	return false
+}
+
+// dropOldestLocked drops the oldest event of the lowest priority that is
+// not a core.ControlEvent, and reports whether there was one.
+func (q *eventQueue) dropOldestLocked() bool {
+	for lane := range q.lanes {
+		for i, it := range q.lanes[lane] {
+			if _, ok := it.evt.(core.ControlEvent); ok {
+				continue
+			}
+			internal.Logger.Debug("Event queue full, dropping oldest event", "event", it.evt)
+			q.lanes[lane] = slices.Delete(q.lanes[lane], i, i+1)
+			q.removedLocked()
+			return true
+		}
+	}
+	return false
+}
+
+func (q *eventQueue) removedLocked() {
+	q.count--
+	close(q.space)
+	q.space = make(chan struct{})
+}
@@ -0,0 +1,272 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+type numberedEvent struct {
+	target uuid.UUID
+	n      int
+}
+
+func (e numberedEvent) TargetNodeId() uuid.UUID {
+	return e.target
+}
+
+type interruptEvent struct {
+	core.DefaultEvent
+}
+
+func (interruptEvent) Priority() Priority {
+	return PriorityInterrupt
+}
+
+type expiringEvent struct {
+	core.DefaultEvent
+	expires time.Time
+}
+
+func (e expiringEvent) ExpiresAt() time.Time {
+	return e.expires
+}
+
+func push(t *testing.T, q EventQueue, events ...core.Event) {
+	t.Helper()
+	for _, evt := range events {
+		if err := q.Push(t.Context(), evt); err != nil {
+			t.Fatalf("Unexpectedly got %v", err)
+		}
+	}
+}
+
+func drain(q EventQueue) []core.Event {
+	var events []core.Event
+	for {
+		evt, ok := q.Pop()
+		if !ok {
+			return events
+		}
+		events = append(events, evt)
+	}
+}
+
+func numbers(t *testing.T, events []core.Event) []int {
+	t.Helper()
+	var ns []int
+	for _, evt := range events {
+		ne, ok := evt.(numberedEvent)
+		if !ok {
+			t.Fatalf("Unexpected event %v", evt)
+		}
+		ns = append(ns, ne.n)
+	}
+	return ns
+}
+
+func TestFIFO(t *testing.T) {
+	q := NewFIFO()
+	for i := range 500 {
+		push(t, q, numberedEvent{n: i})
+	}
+
+	select {
+	case <-q.Ready():
+	default:
+		t.Errorf("Expected queue to be ready")
+	}
+
+	got := numbers(t, drain(q))
+	if len(got) != 500 || got[0] != 0 || got[499] != 499 {
+		t.Errorf("Unexpected order %v", got)
+	}
+}
+
+func TestBoundedDropOldest(t *testing.T) {
+	q := NewBounded(2, DropOldest)
+	push(t, q, numberedEvent{n: 1}, numberedEvent{n: 2}, numberedEvent{n: 3})
+
+	if got := numbers(t, drain(q)); !slices.Equal(got, []int{2, 3}) {
+		t.Errorf("Expected [2 3], got %v", got)
+	}
+}
+
+func TestBoundedReject(t *testing.T) {
+	q := NewBounded(1, Reject)
+	push(t, q, numberedEvent{n: 1})
+
+	if err := q.Push(t.Context(), numberedEvent{n: 2}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+}
+
+func TestBoundedBlock(t *testing.T) {
+	q := NewBounded(1, Block)
+	push(t, q, numberedEvent{n: 1})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	if err := q.Push(ctx, numberedEvent{n: 2}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected push to block until the deadline, got %v", err)
+	}
+
+	pushed := make(chan error)
+	go func() {
+		pushed <- q.Push(t.Context(), numberedEvent{n: 3})
+	}()
+	q.Pop()
+
+	select {
+	case err := <-pushed:
+		if err != nil {
+			t.Errorf("Unexpectedly got %v", err)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Errorf("Push was not unblocked by Pop")
+	}
+}
+
+func TestPriorities(t *testing.T) {
+	q := New(WithPriorities(func(evt core.Event) Priority {
+		if ne, ok := evt.(numberedEvent); ok && ne.n >= 10 {
+			return PriorityHigh
+		}
+		return PriorityNormal
+	}))
+	push(t, q, numberedEvent{n: 1}, numberedEvent{n: 10}, interruptEvent{}, numberedEvent{n: 2}, numberedEvent{n: 11})
+
+	events := drain(q)
+	if _, ok := events[0].(interruptEvent); !ok {
+		t.Errorf("Expected interrupt first, got %v", events[0])
+	}
+	if got := numbers(t, events[1:]); !slices.Equal(got, []int{10, 11, 1, 2}) {
+		t.Errorf("Expected [10 11 1 2], got %v", got)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	q := New(WithTTL(10 * time.Millisecond))
+	push(t, q,
+		numberedEvent{n: 1},
+		expiringEvent{expires: time.Now().Add(time.Hour)},
+	)
+	time.Sleep(20 * time.Millisecond)
+	push(t, q, numberedEvent{n: 2})
+
+	events := drain(q)
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %v", events)
+	}
+	if _, ok := events[0].(expiringEvent); !ok {
+		t.Errorf("Expected event with its own expiry to survive, got %v", events[0])
+	}
+	if got := numbers(t, events[1:]); !slices.Equal(got, []int{2}) {
+		t.Errorf("Expected [2], got %v", got)
+	}
+}
+
+func TestCoalescing(t *testing.T) {
+	target := uuid.New()
+	q := New(WithCoalescing(nil))
+	push(t, q,
+		numberedEvent{target: target, n: 1},
+		numberedEvent{n: 1},
+		numberedEvent{target: target, n: 1},
+		numberedEvent{n: 1},
+		numberedEvent{target: target, n: 2},
+	)
+
+	if got := numbers(t, drain(q)); !slices.Equal(got, []int{1, 1, 1, 2}) {
+		t.Errorf("Expected [1 1 1 2], got %v", got)
+	}
+
+	// A custom match keeps only the latest event for each target.
+	q = New(WithCoalescing(func(queued, incoming core.Event) bool {
+		return queued.TargetNodeId() == incoming.TargetNodeId()
+	}))
+	push(t, q,
+		numberedEvent{target: target, n: 1},
+		numberedEvent{target: target, n: 2},
+		numberedEvent{target: target, n: 3},
+	)
+
+	if got := numbers(t, drain(q)); !slices.Equal(got, []int{3}) {
+		t.Errorf("Expected [3], got %v", got)
+	}
+}
+
+type resultEvent struct {
+	target uuid.UUID
+	result core.ResultDetails
+}
+
+func (e resultEvent) TargetNodeId() uuid.UUID {
+	return e.target
+}
+
+func TestCoalescingUncomparable(t *testing.T) {
+	target := uuid.New()
+	failure := core.FailureResultDetails{Causes: []core.Cause{{FullName: "a"}}}
+	q := New(WithCoalescing(nil))
+	push(t, q,
+		resultEvent{target: target, result: failure},
+		resultEvent{target: target, result: failure},
+	)
+
+	if q.Len() != 2 {
+		t.Errorf("Expected uncomparable events to be queued separately, got %d", q.Len())
+	}
+}
+
+type controlEvent struct {
+	numberedEvent
+}
+
+func (controlEvent) ControlEvent() {}
+
+func TestCoalescingKeepsControlEvents(t *testing.T) {
+	target := uuid.New()
+	q := New(WithCoalescing(func(queued, incoming core.Event) bool {
+		return queued.TargetNodeId() == incoming.TargetNodeId()
+	}))
+	control := controlEvent{numberedEvent{target: target, n: 1}}
+	push(t, q, control, numberedEvent{target: target, n: 2})
+	if events := drain(q); len(events) != 2 || events[0] != control {
+		t.Errorf("Expected the control event to be kept, got %v", events)
+	}
+
+	// A control event may still supersede another.
+	push(t, q, control, controlEvent{numberedEvent{target: target, n: 3}})
+	if events := drain(q); len(events) != 1 || events[0].(controlEvent).n != 3 {
+		t.Errorf("Expected the newer control event, got %v", events)
+	}
+}
+
+func TestControlEventsKept(t *testing.T) {
+	control := core.ErrorEvent{Err: errors.New("control")}
+
+	q := NewBounded(1, Reject)
+	push(t, q, numberedEvent{n: 1}, control)
+	if q.Len() != 2 {
+		t.Errorf("Expected the control event to be queued beyond the capacity, got %d events", q.Len())
+	}
+
+	q = NewBounded(1, DropOldest)
+	push(t, q, control, numberedEvent{n: 1})
+	if events := drain(q); len(events) != 1 || events[0] != control {
+		t.Errorf("Expected the control event to be kept, got %v", events)
+	}
+
+	q = New(WithTTL(time.Millisecond))
+	push(t, q, control)
+	time.Sleep(10 * time.Millisecond)
+	if events := drain(q); len(events) != 1 {
+		t.Errorf("Expected the control event not to expire, got %v", events)
+	}
+}
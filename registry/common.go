@@ -0,0 +1,206 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jbcpollak/greenstalk/v2/common/action"
+	"github.com/jbcpollak/greenstalk/v2/common/composite"
+	"github.com/jbcpollak/greenstalk/v2/common/condition"
+	"github.com/jbcpollak/greenstalk/v2/common/decorator"
+	"github.com/jbcpollak/greenstalk/v2/common/state"
+	"github.com/jbcpollak/greenstalk/v2/common/subtree"
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+// registerCommon registers every node in common/ under the name of its
+// constructor. Nodes whose constructors take more than Params and
+// children get their arguments from the params listed below.
+func registerCommon(r *Registry) {
+	must := func(err error) {
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// action
+	must(RegisterLeaf(r, "Succeed", action.Succeed))
+	must(RegisterLeaf(r, "Fail", action.Fail))
+	must(RegisterLeaf(r, "Counter", action.Counter))
+	must(RegisterLeaf(r, "FunctionAction", action.FunctionAction))
+	must(RegisterLeaf(r, "AsyncFunctionAction", action.AsyncFunctionAction))
+	// Signaller sends on a chan any. Register action.Signaller[T] under
+	// another name for other channel types.
+	must(RegisterLeaf(r, "Signaller", action.Signaller[any]))
+
+	// composite
+	must(RegisterComposite(r, "Sequence", composite.SequenceNamed))
+	must(RegisterComposite(r, "Selector", composite.SelectorNamed))
+	must(RegisterComposite(r, "ActiveSequence", composite.ActiveSequenceNamed))
+	must(RegisterComposite(r, "PersistentSequence", composite.PersistentSequenceNamed))
+	must(RegisterComposite(r, "RandomSequence", composite.RandomSequenceNamed))
+	must(RegisterComposite(r, "RandomSelector", composite.RandomSelectorNamed))
+	// Parallel: successRequired, failureRequired.
+	must(r.Register("Parallel", func(spec Spec) (core.Node, error) {
+		var params struct {
+			SuccessRequired int
+			FailureRequired int
+		}
+		if err := spec.Decode(&params); err != nil {
+			return nil, err
+		}
+		return composite.ParallelNamed(spec.NameOr("Parallel"), params.SuccessRequired, params.FailureRequired, spec.Children...), nil
+	}))
+
+	// condition
+	// Switch: func, a func() int.
+	must(r.Register("Switch", func(spec Spec) (core.Node, error) {
+		var params struct {
+			Func condition.SwitchFunc[int]
+		}
+		if err := spec.Decode(&params); err != nil {
+			return nil, err
+		}
+		return condition.SwitchNamed(spec.NameOr("Switch"), params.Func, spec.Children...), nil
+	}))
+	// SwitchMap: func, a func() string, and keys, the key of each child.
+	must(r.Register("SwitchMap", func(spec Spec) (core.Node, error) {
+		var params struct {
+			Func condition.SwitchFunc[string]
+			Keys []string
+		}
+		if err := spec.Decode(&params); err != nil {
+			return nil, err
+		}
+		if len(params.Keys) != len(spec.Children) {
+			return nil, fmt.Errorf("got %d keys for %d children", len(params.Keys), len(spec.Children))
+		}
+		children := make(map[string]core.Node, len(params.Keys))
+		for i, key := range params.Keys {
+			if _, ok := children[key]; ok {
+				return nil, fmt.Errorf("duplicate key %q", key)
+			}
+			children[key] = spec.Children[i]
+		}
+		return condition.SwitchMapNamed(spec.NameOr("SwitchMap"), params.Func, children), nil
+	}))
+
+	// decorator
+	must(r.Register("Inverter", namedDecorator("Inverter", decorator.InverterNamed)))
+	must(r.Register("UntilFailure", namedDecorator("UntilFailure", decorator.UntilFailureNamed)))
+	must(r.Register("UntilSuccess", namedDecorator("UntilSuccess", decorator.UntilSuccessNamed)))
+	must(r.Register("Transaction", namedDecorator("Transaction", decorator.TransactionNamed)))
+	must(RegisterDecorator(r, "RepeatUntil", decorator.RepeatUntil))
+	must(RegisterDecorator(r, "Repeat", decorator.Repeat))
+	must(RegisterDecorator(r, "Retry", decorator.Retry))
+	must(RegisterDecorator(r, "Delayer", decorator.Delayer))
+	must(RegisterDecorator(r, "AsyncDelayer", decorator.AsyncDelayer))
+	// With: create, which opens a resource and returns the function that
+	// closes it.
+	must(r.Register("With", func(spec Spec) (core.Node, error) {
+		child, err := spec.Child()
+		if err != nil {
+			return nil, err
+		}
+		var params struct {
+			Create func(context.Context) (func(context.Context) error, error)
+		}
+		if err := spec.Decode(&params); err != nil {
+			return nil, err
+		}
+		return decorator.WithNamed(spec.NameOr("With"), params.Create, child), nil
+	}))
+	// WithAsync: enter and exit, both a func(context.Context) error.
+	must(r.Register("WithAsync", func(spec Spec) (core.Node, error) {
+		child, err := spec.Child()
+		if err != nil {
+			return nil, err
+		}
+		var params struct {
+			Enter func(context.Context) error
+			Exit  func(context.Context) error
+		}
+		if err := spec.Decode(&params); err != nil {
+			return nil, err
+		}
+		return decorator.WithAsyncNamed(spec.NameOr("WithAsync"), params.Enter, params.Exit, child), nil
+	}))
+	// DynamicDecorator: childFn, a func() (core.Node, error).
+	must(r.Register("DynamicDecorator", func(spec Spec) (core.Node, error) {
+		if len(spec.Children) != 0 {
+			return nil, fmt.Errorf("DynamicDecorator creates its own child, got %d", len(spec.Children))
+		}
+		var params struct {
+			ChildFn func() (core.Node, error)
+		}
+		if err := spec.Decode(&params); err != nil {
+			return nil, err
+		}
+		return decorator.DynamicDecoratorNamed(spec.NameOr("DynamicDecorator"), params.ChildFn), nil
+	}))
+
+	// subtree
+	// SubTree: library, template, params, remap, which maps the
+	// template's inputs and outputs to the names of registered state, and
+	// blackboard, which maps keys of the subtree's scope to the parent's.
+	must(r.Register("SubTree", func(spec Spec) (core.Node, error) {
+		if len(spec.Children) != 0 {
+			return nil, fmt.Errorf("SubTree builds its own child, got %d", len(spec.Children))
+		}
+		var params struct {
+			Library    *subtree.Library
+			Template   string
+			Params     map[string]any
+			Remap      map[string]string
+			Blackboard map[string]string
+		}
+		if err := spec.Decode(&params); err != nil {
+			return nil, err
+		}
+		remap := make(map[string]any, len(params.Remap))
+		for port, name := range params.Remap {
+			value, err := spec.Value(name)
+			if err != nil {
+				return nil, fmt.Errorf("remapping %q: %w", port, err)
+			}
+			remap[port] = value
+		}
+		return subtree.SubTree(subtree.SubTreeParams{
+			BaseParams: core.BaseParams(spec.Name),
+			Library:    params.Library,
+			Template:   params.Template,
+			Params:     params.Params,
+			Remap:      remap,
+			Blackboard: params.Blackboard,
+		}), nil
+	}))
+
+	// state
+	// StateReset: states, a list of state.StateResetter values.
+	must(r.Register("StateReset", func(spec Spec) (core.Node, error) {
+		if len(spec.Children) != 0 {
+			return nil, fmt.Errorf("leaf takes no children, got %d", len(spec.Children))
+		}
+		var params struct {
+			States []state.StateResetter
+		}
+		if err := spec.Decode(&params); err != nil {
+			return nil, err
+		}
+		return state.MakeStateResetAction(params.States...), nil
+	}))
+}
+
+// namedDecorator adapts a decorator constructor taking a name and child.
+func namedDecorator(typeName string, ctor func(string, core.Node) core.Node) Constructor {
+	return func(spec Spec) (core.Node, error) {
+		child, err := spec.Child()
+		if err != nil {
+			return nil, err
+		}
+		if err := spec.Decode(&struct{}{}); err != nil {
+			return nil, err
+		}
+		return ctor(spec.NameOr(typeName), child), nil
+	}
+}
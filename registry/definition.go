@@ -0,0 +1,122 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+// Definition declares a node and, recursively, its children.
+type Definition struct {
+	Type     string         `json:"type" yaml:"type"`
+	Name     string         `json:"name,omitempty" yaml:"name,omitempty"`
+	Params   map[string]any `json:"params,omitempty" yaml:"params,omitempty"`
+	Children []Definition   `json:"children,omitempty" yaml:"children,omitempty"`
+}
+
+// Spec is what a Constructor builds a node from: its definition, with the
+// children already built.
+type Spec struct {
+	Type     string
+	Name     string
+	Params   map[string]any
+	Children []core.Node
+
+	registry *Registry
+}
+
+// NameOr returns the node's name, or name if it has none.
+func (s Spec) NameOr(name string) string {
+	if s.Name == "" {
+		return name
+	}
+	return s.Name
+}
+
+// Child returns the node's only child.
+func (s Spec) Child() (core.Node, error) {
+	if len(s.Children) != 1 {
+		return nil, fmt.Errorf("expected exactly one child, got %d", len(s.Children))
+	}
+	return s.Children[0], nil
+}
+
+// Value returns the registered value called name.
+func (s Spec) Value(name string) (any, error) {
+	value, ok := s.registry.values[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown value %q", name)
+	}
+	return value, nil
+}
+
+// Decode decodes the node's params into params, which must point to a
+// struct. An embedded core.BaseParams is set to the node's name.
+func (s Spec) Decode(params any) error {
+	return decodeParams(params, s.Name, s.Params, s.registry.values)
+}
+
+// Build builds the tree declared by def.
+func (r *Registry) Build(def Definition) (core.Node, error) {
+	return r.build(def, "")
+}
+
+func (r *Registry) build(def Definition, path string) (core.Node, error) {
+	path = definitionPath(def, path)
+
+	ctor, ok := r.constructors[def.Type]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown node type %q", path, def.Type)
+	}
+
+	children := make([]core.Node, len(def.Children))
+	for i, childDef := range def.Children {
+		child, err := r.build(childDef, path+"["+strconv.Itoa(i)+"]")
+		if err != nil {
+			return nil, err
+		}
+		children[i] = child
+	}
+
+	node, err := ctor(Spec{
+		Type:     def.Type,
+		Name:     def.Name,
+		Params:   def.Params,
+		Children: children,
+		registry: r,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return node, nil
+}
+
+// definitionPath describes where def is in the document for errors.
+func definitionPath(def Definition, parent string) string {
+	label := def.Type
+	if def.Name != "" {
+		label += " " + strconv.Quote(def.Name)
+	}
+	if parent == "" {
+		return label
+	}
+	return parent + "." + label
+}
+
+// Load decodes a document with unmarshal and builds the tree it declares.
+// Pass yaml.Unmarshal from a YAML library to load YAML documents; nested
+// mappings may be decoded as map[any]any, as gopkg.in/yaml.v2 does.
+func (r *Registry) Load(data []byte, unmarshal func([]byte, any) error) (core.Node, error) {
+	var def Definition
+	if err := unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("decoding tree definition: %w", err)
+	}
+	return r.Build(def)
+}
+
+// LoadJSON builds the tree declared by a JSON document.
+func (r *Registry) LoadJSON(data []byte) (core.Node, error) {
+	return r.Load(data, json.Unmarshal)
+}
@@ -0,0 +1,23 @@
+// Package registry builds behavior trees from declarative definitions.
+//
+// A Registry maps node type names to constructors. A Definition, usually
+// decoded from a JSON or YAML document, names a type for each node along
+// with its params and children:
+//
+//	{
+//	  "type": "Sequence",
+//	  "name": "Patrol",
+//	  "children": [
+//	    {"type": "Delayer", "params": {"delay": "500ms"}, "children": [
+//	      {"type": "FunctionAction", "name": "Move", "params": {"func": "move"}}
+//	    ]}
+//	  ]
+//	}
+//
+// Params are decoded into the node's typed Params struct. Fields holding
+// functions, channels or interfaces can't be written in a document, so
+// they are given as the name of a value registered with RegisterValue.
+// Every node in common/ is registered by New, and custom nodes can be
+// added with Register, RegisterLeaf, RegisterDecorator and
+// RegisterComposite.
+package registry
@@ -0,0 +1,252 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+var (
+	baseParamsType = reflect.TypeFor[core.BaseParams]()
+	durationType   = reflect.TypeFor[time.Duration]()
+)
+
+// decodeParams decodes raw into the struct params points to. Keys match
+// field names case-insensitively, or their json tag. Durations must be
+// strings such as "1.5s". Numbers and booleans may be given as strings,
+// lists of them as strings separated by semicolons, and fields that can't
+// be written in a document name a registered value.
+func decodeParams(params any, name string, raw map[string]any, values map[string]any) error {
+	v := reflect.ValueOf(params)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("params must point to a struct, got %T", params)
+	}
+	v = v.Elem()
+
+	fields := map[string]reflect.Value{}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Type == baseParamsType {
+			v.Field(i).SetString(name)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		fields[strings.ToLower(field.Name)] = v.Field(i)
+		if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag != "" && tag != "-" {
+			fields[strings.ToLower(tag)] = v.Field(i)
+		}
+	}
+
+	var errs []error
+	for key, value := range raw {
+		field, ok := fields[strings.ToLower(key)]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown param %q", key))
+			continue
+		}
+		if err := decodeValue(field, stringKeys(value), values); err != nil {
+			errs = append(errs, fmt.Errorf("param %q: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stringKeys converts the map[any]any that YAML libraries such as
+// gopkg.in/yaml.v2 decode nested mappings to, at any depth of raw, into
+// the map[string]any encoding/json handles.
+func stringKeys(raw any) any {
+	switch v := raw.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = stringKeys(value)
+		}
+		return m
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			m[key] = stringKeys(value)
+		}
+		return m
+	case []any:
+		s := make([]any, len(v))
+		for i, value := range v {
+			s[i] = stringKeys(value)
+		}
+		return s
+	}
+	return raw
+}
+
+func decodeValue(field reflect.Value, raw any, values map[string]any) error {
+	t := field.Type()
+
+	switch {
+	case t == durationType:
+		d, err := decodeDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	case isReference(t):
+		if t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+			// Plain data is fine for an any, but a string may still name
+			// a registered value.
+			if name, ok := raw.(string); ok {
+				if value, ok := values[name]; ok {
+					field.Set(reflect.ValueOf(value))
+					return nil
+				}
+			}
+			if raw != nil {
+				field.Set(reflect.ValueOf(raw))
+			}
+			return nil
+		}
+		return decodeReference(field, raw, values)
+	case t.Kind() == reflect.Slice && isReference(t.Elem()):
+		names, ok := raw.([]any)
+		if !ok {
+			return fmt.Errorf("expected a list of value names, got %T", raw)
+		}
+		slice := reflect.MakeSlice(t, len(names), len(names))
+		for i, name := range names {
+			if err := decodeReference(slice.Index(i), name, values); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	if s, ok := raw.(string); ok && isScalar(t) {
+		// Formats such as XML only have strings.
+		return parseScalar(field, s)
+	}
+	if s, ok := raw.(string); ok && isList(t) {
+		// Lists are separated by semicolons, as in BehaviorTree.CPP.
+		var items []string
+		if s != "" {
+			items = strings.Split(s, ";")
+		}
+		slice := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			if t.Elem().Kind() == reflect.String {
+				slice.Index(i).SetString(item)
+			} else if err := parseScalar(slice.Index(i), item); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, field.Addr().Interface())
+}
+
+// isReference reports whether values of type t must be registered with
+// RegisterValue rather than written in a document.
+func isReference(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Func, reflect.Chan, reflect.Interface, reflect.Pointer, reflect.UnsafePointer:
+		return true
+	}
+	return false
+}
+
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isList reports whether t is a slice of strings or scalars, other than
+// []byte.
+func isList(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice || t.Elem().Kind() == reflect.Uint8 {
+		return false
+	}
+	return t.Elem().Kind() == reflect.String || isScalar(t.Elem())
+}
+
+func parseScalar(field reflect.Value, s string) error {
+	t := field.Type()
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	}
+	return nil
+}
+
+func decodeReference(field reflect.Value, raw any, values map[string]any) error {
+	name, ok := raw.(string)
+	if !ok {
+		return fmt.Errorf("expected the name of a registered value, got %T", raw)
+	}
+	value, ok := values[name]
+	if !ok {
+		return fmt.Errorf("unknown value %q", name)
+	}
+
+	v := reflect.ValueOf(value)
+	switch {
+	case !v.IsValid():
+		field.SetZero()
+	case v.Type().AssignableTo(field.Type()):
+		field.Set(v)
+	case v.Type().ConvertibleTo(field.Type()) && v.Kind() == field.Kind():
+		// Lets plain funcs fill named func types such as SwitchFunc.
+		field.Set(v.Convert(field.Type()))
+	default:
+		return fmt.Errorf("value %q is a %s, expected %s", name, v.Type(), field.Type())
+	}
+	return nil
+}
+
+// decodeDuration decodes a duration written with its unit. Bare numbers
+// are rejected rather than guessing their unit.
+func decodeDuration(raw any) (time.Duration, error) {
+	if d, ok := raw.(string); ok {
+		return time.ParseDuration(d)
+	}
+	return 0, fmt.Errorf("expected a duration such as \"1.5s\", got %T", raw)
+}
@@ -0,0 +1,105 @@
+package registry
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+// Constructor builds a node from its Spec.
+type Constructor func(spec Spec) (core.Node, error)
+
+// Registry maps node type names to constructors, and value names to the
+// functions, channels and state referenced by params. It is not safe to
+// register concurrently with Build.
+type Registry struct {
+	constructors map[string]Constructor
+	values       map[string]any
+}
+
+// New returns a registry with every node in common/ registered.
+func New() *Registry {
+	r := Empty()
+	registerCommon(r)
+	return r
+}
+
+// Empty returns a registry with no types registered.
+func Empty() *Registry {
+	return &Registry{
+		constructors: map[string]Constructor{},
+		values:       map[string]any{},
+	}
+}
+
+// Register adds a node type. It fails if typeName is already registered.
+func (r *Registry) Register(typeName string, ctor Constructor) error {
+	if _, ok := r.constructors[typeName]; ok {
+		return fmt.Errorf("node type %q is already registered", typeName)
+	}
+	r.constructors[typeName] = ctor
+	return nil
+}
+
+// RegisterValue makes value available to params under name. Params fields
+// that can't be written in a document, such as functions, channels and
+// interfaces, name the value to use instead.
+func (r *Registry) RegisterValue(name string, value any) error {
+	if _, ok := r.values[name]; ok {
+		return fmt.Errorf("value %q is already registered", name)
+	}
+	r.values[name] = value
+	return nil
+}
+
+// Types returns the registered node types, sorted.
+func (r *Registry) Types() []string {
+	return slices.Sorted(maps.Keys(r.constructors))
+}
+
+// RegisterLeaf registers a leaf built from its Params, like
+// action.Succeed.
+func RegisterLeaf[P core.Params, N core.Node](r *Registry, typeName string, ctor func(P) N) error {
+	return r.Register(typeName, func(spec Spec) (core.Node, error) {
+		if len(spec.Children) != 0 {
+			return nil, fmt.Errorf("leaf takes no children, got %d", len(spec.Children))
+		}
+		var params P
+		if err := spec.Decode(&params); err != nil {
+			return nil, err
+		}
+		return ctor(params), nil
+	})
+}
+
+// RegisterDecorator registers a decorator built from its Params and
+// child, like decorator.Delayer. Nodes without a name are named after
+// typeName.
+func RegisterDecorator[P core.Params, N core.Node](r *Registry, typeName string, ctor func(P, core.Node) N) error {
+	return r.Register(typeName, func(spec Spec) (core.Node, error) {
+		child, err := spec.Child()
+		if err != nil {
+			return nil, err
+		}
+		spec.Name = spec.NameOr(typeName)
+		var params P
+		if err := spec.Decode(&params); err != nil {
+			return nil, err
+		}
+		return ctor(params, child), nil
+	})
+}
+
+// RegisterComposite registers a composite built from its name and
+// children, like composite.SequenceNamed. Nodes without a name are named
+// after typeName.
+func RegisterComposite[N core.Node](r *Registry, typeName string, ctor func(name string, children ...core.Node) N) error {
+	return r.Register(typeName, func(spec Spec) (core.Node, error) {
+		if err := spec.Decode(&struct{}{}); err != nil {
+			return nil, err
+		}
+		return ctor(spec.NameOr(typeName), spec.Children...), nil
+	})
+}
@@ -0,0 +1,227 @@
+package registry_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jbcpollak/greenstalk/v2"
+	"github.com/jbcpollak/greenstalk/v2/common/action"
+	"github.com/jbcpollak/greenstalk/v2/core"
+	"github.com/jbcpollak/greenstalk/v2/registry"
+	"gopkg.in/yaml.v2"
+)
+
+const patrol = `{
+	"type": "Sequence",
+	"name": "Patrol",
+	"children": [
+		{"type": "AsyncDelayer", "name": "Wait", "params": {"delay": "1ms"}, "children": [
+			{"type": "FunctionAction", "name": "Move", "params": {"func": "move"}}
+		]},
+		{"type": "Selector", "children": [
+			{"type": "Fail"},
+			{"type": "Counter", "name": "Count", "params": {"limit": 5, "countChan": "counts"}}
+		]},
+		{"type": "Switch", "params": {"func": "second"}, "children": [
+			{"type": "Fail"},
+			{"type": "Succeed", "name": "Chosen"}
+		]}
+	]
+}`
+
+func TestLoadJSON(t *testing.T) {
+	moved := 0
+	counts := make(chan uint, 1)
+
+	r := registry.New()
+	if err := r.RegisterValue("move", func() core.ResultDetails {
+		moved++
+		return core.SuccessResult()
+	}); err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	r.RegisterValue("counts", counts)
+	r.RegisterValue("second", func() int { return 1 })
+
+	root, err := r.LoadJSON([]byte(patrol))
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	tree, err := greenstalk.NewBehaviorTree(root, greenstalk.WithValidation())
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	for _, path := range []string{"Patrol.Wait.Move", "Patrol.Selector.Count", "Patrol.Switch.SucceedChosen"} {
+		if _, ok := tree.NodeByPath(path); !ok {
+			t.Errorf("Expected a node at %s", path)
+		}
+	}
+
+	result, err := tree.RunToCompletion(t.Context(), core.DefaultEvent{})
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	if result.Status() != core.StatusSuccess {
+		t.Errorf("Expected success, got %v", result)
+	}
+	if moved != 1 {
+		t.Errorf("Expected the registered function to run once, ran %d times", moved)
+	}
+	if count := <-counts; count != 1 {
+		t.Errorf("Expected the counter to count once, got %d", count)
+	}
+}
+
+type greetParams struct {
+	core.BaseParams
+
+	Greeting string
+	Greeted  *[]string
+}
+
+type greet struct {
+	core.Leaf[greetParams]
+}
+
+func (g *greet) Activate(ctx context.Context, evt core.Event) core.ResultDetails {
+	*g.Params.Greeted = append(*g.Params.Greeted, g.Params.Greeting)
+	return core.SuccessResult()
+}
+
+func (g *greet) Tick(ctx context.Context, evt core.Event) core.ResultDetails {
+	return core.SuccessResult()
+}
+
+func (g *greet) Leave(context.Context) error {
+	return nil
+}
+
+func TestRegisterLeaf(t *testing.T) {
+	var greeted []string
+
+	r := registry.New()
+	err := registry.RegisterLeaf(r, "Greet", func(params greetParams) *greet {
+		return &greet{Leaf: core.NewLeaf(params)}
+	})
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	r.RegisterValue("greeted", &greeted)
+
+	if err := registry.RegisterLeaf(r, "Greet", action.Succeed); err == nil {
+		t.Errorf("Expected registering Greet twice to fail")
+	}
+
+	root, err := r.Build(registry.Definition{
+		Type: "Sequence",
+		Children: []registry.Definition{
+			{Type: "Greet", Name: "Hello", Params: map[string]any{"greeting": "hello", "greeted": "greeted"}},
+			{Type: "Greet", Name: "World", Params: map[string]any{"greeting": "world", "greeted": "greeted"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	tree, err := greenstalk.NewBehaviorTree(root)
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	tree.Update(t.Context(), core.DefaultEvent{})
+
+	if strings.Join(greeted, " ") != "hello world" {
+		t.Errorf("Expected both leaves to run, got %v", greeted)
+	}
+}
+
+const route = `
+type: Sequence
+children:
+  - type: Route
+    name: Loop
+    params:
+      # YAML 1.1 reads a bare y as true.
+      waypoints:
+        - {x: 1, "y": 2}
+        - {x: 3, "y": 4}
+      speeds:
+        1: 0.5
+        2: 1
+      tags:
+        kind: patrol
+  - type: AsyncDelayer
+    params:
+      delay: 1ms
+    children:
+      - type: Succeed
+`
+
+type point struct {
+	X, Y int
+}
+
+type routeParams struct {
+	core.BaseParams
+
+	Waypoints []point
+	Speeds    map[int]float64
+	Tags      any
+}
+
+func TestLoadYAML(t *testing.T) {
+	var got routeParams
+	r := registry.New()
+	err := registry.RegisterLeaf(r, "Route", func(params routeParams) *greet {
+		got = params
+		return &greet{Leaf: core.NewLeaf(greetParams{BaseParams: params.BaseParams})}
+	})
+	if err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+
+	if _, err := r.Load([]byte(route), yaml.Unmarshal); err != nil {
+		t.Fatalf("Unexpectedly got %v", err)
+	}
+	expected := routeParams{
+		BaseParams: "Loop",
+		Waypoints:  []point{{1, 2}, {3, 4}},
+		Speeds:     map[int]float64{1: 0.5, 2: 1},
+		Tags:       map[string]any{"kind": "patrol"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	r := registry.New()
+
+	cases := []struct {
+		name     string
+		document string
+		expected string
+	}{
+		{"unknown type", `{"type": "Sequence", "children": [{"type": "Teleport"}]}`, `Sequence[0].Teleport: unknown node type "Teleport"`},
+		{"unknown param", `{"type": "Delayer", "params": {"delay": "1s", "speed": 2}, "children": [{"type": "Succeed"}]}`, `unknown param "speed"`},
+		{"bad duration", `{"type": "Delayer", "params": {"delay": "soon"}, "children": [{"type": "Succeed"}]}`, `param "delay"`},
+		{"duration without unit", `{"type": "Delayer", "params": {"delay": 1000}, "children": [{"type": "Succeed"}]}`, `param "delay": expected a duration such as "1.5s", got float64`},
+		{"unknown value", `{"type": "FunctionAction", "name": "Act", "params": {"func": "missing"}}`, `FunctionAction "Act": param "func": unknown value "missing"`},
+		{"missing child", `{"type": "Inverter"}`, `expected exactly one child, got 0`},
+		{"leaf children", `{"type": "Succeed", "children": [{"type": "Succeed"}]}`, `leaf takes no children`},
+	}
+	for _, c := range cases {
+		_, err := r.LoadJSON([]byte(c.document))
+		if err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", c.name, c.expected, err)
+		}
+	}
+
+	r.RegisterValue("notAFunc", 42)
+	_, err := r.LoadJSON([]byte(`{"type": "FunctionAction", "params": {"func": "notAFunc"}}`))
+	if err == nil || !strings.Contains(err.Error(), "is a int") {
+		t.Errorf("Expected a type mismatch, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package greenstalk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+// ErrTreeShutdown is returned when running a tree that has been shut down.
+var ErrTreeShutdown = errors.New("behavior tree has been shut down")
+
+// Shutdown stops the tree deterministically. It stops a running EventLoop
+// and waits for it to return, halts every Running node so that their Leave
+// methods run (closing decorator.With resources, running WithAsync exit
+// functions, ...), cancels outstanding RunningFns and waits for them to
+// return. Any errors queued but not yet processed, or returned by RunningFns
+// while draining, are reported in the aggregated error.
+//
+// If ctx expires before the tree has drained, Shutdown returns early with
+// an error wrapping ctx.Err(). Shutdown may only be called once, later
+// calls return ErrTreeShutdown.
+func (bt *Tree) Shutdown(ctx context.Context) error {
+	bt.mu.Lock()
+	if bt.isShutdown {
+		bt.mu.Unlock()
+		return ErrTreeShutdown
+	}
+	bt.isShutdown = true
+	close(bt.stop)
+	loopDone := bt.loopDone
+	bt.mu.Unlock()
+
+	if loopDone != nil {
+		select {
+		case <-loopDone:
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for event loop: %w", ctx.Err())
+		}
+	}
+
+	// The event loop is stopped, so nodes can safely be halted from here.
+	errs := []error{core.Halt(ctx, bt.root)}
+	bt.publish()
+	// Nothing pops the queue anymore, so RunningFns must not wait to push.
+	bt.stopRunning()
+
+	drained := make(chan struct{})
+	go func() {
+		bt.running.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("waiting for running functions: %w", ctx.Err()))
+	}
+
+	// Report errors that never made it through the event loop.
+	for evt, ok := bt.events.Pop(); ok; evt, ok = bt.events.Pop() {
+		switch errEvt := evt.(type) {
+		case core.ErrorEvent:
+			errs = append(errs, errEvt.Err)
+		case core.RunningErrorEvent:
+			errs = append(errs, errEvt.Err)
+		}
+	}
+
+	bt.mu.Lock()
+	errs = append(errs, bt.shutdownErr...)
+	bt.mu.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// Close shuts the tree down without a deadline, see Shutdown.
+func (bt *Tree) Close() error {
+	return bt.Shutdown(context.Background())
+}
+
+func (bt *Tree) startLoop() error {
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	if bt.isShutdown {
+		return ErrTreeShutdown
+	}
+	bt.loopDone = make(chan struct{})
+	return nil
+}
+
+func (bt *Tree) endLoop() {
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	close(bt.loopDone)
+	bt.loopDone = nil
+}
+
+// recordShutdownError keeps err for Shutdown to report if the tree is
+// shutting down, in which case the event loop will not process it.
+func (bt *Tree) recordShutdownError(err error) bool {
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	if bt.isShutdown {
+		bt.shutdownErr = append(bt.shutdownErr, err)
+	}
+	return bt.isShutdown
+}
@@ -0,0 +1,65 @@
+package greenstalk
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/jbcpollak/greenstalk/v2/core"
+)
+
+// TreeTemplate is the shared definition of a behavior tree, from which any
+// number of trees can be instantiated, e.g. one per agent. Instances are
+// copies of the template's nodes made with core.Clone, which share the
+// template's Params and only allocate their own ids and runtime state, so
+// the tree doesn't have to be built through its constructors again.
+type TreeTemplate struct {
+	root      core.Node
+	opts      []TreeOption
+	validated bool
+}
+
+// NewTreeTemplate creates a template from a tree that has not been run.
+// The template keeps a copy of the tree, so root may be used afterwards
+// without affecting instances. opts are applied to every instance;
+// options that give a tree its own objects, such as WithEventQueue, must
+// be passed to Instantiate instead.
+// If opts include WithValidation, the tree is validated once, here,
+// rather than for every instance.
+func NewTreeTemplate(root core.Node, opts ...TreeOption) (*TreeTemplate, error) {
+	if root.Result().Status() != core.StatusInvalid {
+		return nil, errors.New("NewTreeTemplate: the tree has already run")
+	}
+	clone, err := core.Clone(root)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := NewBehaviorTree(clone, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &TreeTemplate{root: tree.root, opts: opts, validated: tree.validate}, nil
+}
+
+// Instantiate creates a new tree from the template. opts are applied after
+// the template's. Instances are only validated if WithValidation is passed
+// here and was not passed to NewTreeTemplate.
+func (t *TreeTemplate) Instantiate(opts ...TreeOption) (*Tree, error) {
+	root, err := core.Clone(t.root)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := newTree(root, append(slices.Clip(t.opts), opts...))
+	if err := errors.Join(tree.optionErrs...); err != nil {
+		return nil, err
+	}
+	if tree.validate && !t.validated {
+		if err := tree.Validate(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+	tree.index.build(root)
+	tree.publish()
+	return tree, nil
+}
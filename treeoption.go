@@ -1,7 +1,12 @@
 package greenstalk
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/jbcpollak/greenstalk/v2/blackboard"
 	"github.com/jbcpollak/greenstalk/v2/core"
+	"github.com/jbcpollak/greenstalk/v2/queue"
 )
 
 // TreeOption is used to set options when initializing a BehaviorTree.
@@ -18,3 +23,70 @@ func WithVisitors(v ...core.Visitor) TreeOption {
 		p.visitors = v
 	}
 }
+
+// WithTickInterval makes EventLoop tick the root every interval with a
+// core.TickEvent, in addition to processing queued events. This lets
+// clock-driven nodes such as decorator.Delayer progress on their own.
+// NewBehaviorTree fails if interval is not positive.
+func WithTickInterval(interval time.Duration) TreeOption {
+	return func(p *Tree) {
+		if interval <= 0 {
+			p.optionErrs = append(p.optionErrs, fmt.Errorf("WithTickInterval: interval must be positive, got %v", interval))
+			return
+		}
+		p.clock = newTickClock(interval, interval)
+	}
+}
+
+// WithAdaptiveTickInterval is like WithTickInterval, but the interval backs
+// off from minInterval up to maxInterval while ticks change nothing, and
+// returns to minInterval as soon as an event is processed or the root
+// changes status. NewBehaviorTree fails if minInterval is not positive or
+// maxInterval is smaller than minInterval.
+func WithAdaptiveTickInterval(minInterval, maxInterval time.Duration) TreeOption {
+	return func(p *Tree) {
+		if minInterval <= 0 {
+			p.optionErrs = append(p.optionErrs, fmt.Errorf("WithAdaptiveTickInterval: minimum interval must be positive, got %v", minInterval))
+			return
+		}
+		if maxInterval < minInterval {
+			p.optionErrs = append(p.optionErrs, fmt.Errorf("WithAdaptiveTickInterval: maximum interval %v is smaller than minimum %v", maxInterval, minInterval))
+			return
+		}
+		p.clock = newTickClock(minInterval, maxInterval)
+	}
+}
+
+// WithEventQueue replaces the tree's default event queue, a FIFO bounded to
+// 100 events that blocks when full, with q.
+func WithEventQueue(q queue.EventQueue) TreeOption {
+	return func(p *Tree) {
+		p.events = q
+	}
+}
+
+// WithPanicPolicy sets how panics raised by nodes, and by the RunningFns
+// they return, are handled. The default is core.PanicErrorTree, which
+// turns them into errors carrying the node's name, id and stack trace.
+func WithPanicPolicy(policy core.PanicPolicy) TreeOption {
+	return func(p *Tree) {
+		p.panics = policy
+	}
+}
+
+// WithAsyncErrorPolicy sets the result a node gets when a RunningFn it
+// returned fails. The default is core.AsyncErrorResult, which errors the
+// node; the error only ends the tree once it propagates to the root.
+func WithAsyncErrorPolicy(policy core.AsyncErrorPolicy) TreeOption {
+	return func(p *Tree) {
+		p.asyncErrs = policy
+	}
+}
+
+// WithBlackboard makes b available to the tree's nodes, and to the
+// RunningFns they return, through blackboard.FromContext.
+func WithBlackboard(b *blackboard.Blackboard) TreeOption {
+	return func(p *Tree) {
+		p.blackboard = b
+	}
+}
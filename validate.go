@@ -0,0 +1,51 @@
+package greenstalk
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jbcpollak/greenstalk/v2/core"
+	"github.com/jbcpollak/greenstalk/v2/internal"
+)
+
+// WithValidation makes NewBehaviorTree validate the tree, see Validate,
+// and fail if it is invalid.
+func WithValidation() TreeOption {
+	return func(p *Tree) {
+		p.validate = true
+	}
+}
+
+// Validate checks the whole tree before it runs and returns every problem
+// found at once. It reports node instances used more than once, nodes
+// sharing a full name, and whatever the nodes implementing core.Validator
+// report about themselves, such as nil children, empty composites,
+// unreachable Parallel thresholds or invalid Params.
+func (bt *Tree) Validate(ctx context.Context) error {
+	var eb internal.ErrorBuilder
+	eb.SetMessage("Validate")
+
+	seen := map[uuid.UUID]bool{}
+	names := map[string]bool{}
+
+	core.WalkNodes(bt.root, func(node core.Node, level int) {
+		if seen[node.Id()] {
+			eb.Write("node %s is used more than once", node.FullName())
+			return
+		}
+		seen[node.Id()] = true
+
+		if names[node.FullName()] {
+			eb.Write("more than one node is named %s", node.FullName())
+		}
+		names[node.FullName()] = true
+
+		if v, ok := node.(core.Validator); ok {
+			if err := v.Validate(ctx); err != nil {
+				eb.Write("%s: %v", node.FullName(), err)
+			}
+		}
+	})
+
+	return eb.Error()
+}
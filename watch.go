@@ -0,0 +1,129 @@
+package greenstalk
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jbcpollak/greenstalk/v2/core"
+	"github.com/jbcpollak/greenstalk/v2/internal"
+)
+
+// Subscriber is the part of state.Subscribable Watch needs. The common
+// state providers, such as state.StateProvider, implement it.
+type Subscriber[T any] interface {
+	Subscribe(fn func(T)) (unsubscribe func())
+}
+
+// WatchOption is used to filter the changes Watch turns into events.
+type WatchOption[T any] func(*watchConfig[T])
+
+type watchConfig[T any] struct {
+	equal    func(a, b T) bool
+	debounce time.Duration
+}
+
+// WatchEqual skips values equal to the last one an event was enqueued
+// for.
+func WatchEqual[T any](equal func(a, b T) bool) WatchOption[T] {
+	return func(c *watchConfig[T]) {
+		c.equal = equal
+	}
+}
+
+// WatchDistinct is WatchEqual for comparable values.
+func WatchDistinct[T comparable]() WatchOption[T] {
+	return WatchEqual(func(a, b T) bool { return a == b })
+}
+
+// WatchDebounce waits until the value has not changed for d, and enqueues
+// a single event for the latest value.
+func WatchDebounce[T any](d time.Duration) WatchOption[T] {
+	return func(c *watchConfig[T]) {
+		c.debounce = d
+	}
+}
+
+// Watch enqueues the event returned by evt on bt each time s changes, so
+// nodes depending on s are updated as soon as it does. evt can return a
+// core.TargetNodeEvent to update a single node, or a broadcast event.
+// Watching stops when ctx is done, when the returned function is called,
+// or when the tree is shut down.
+func Watch[T any](ctx context.Context, bt *Tree, s Subscriber[T], evt func(T) core.Event, opts ...WatchOption[T]) (stop func()) {
+	var config watchConfig[T]
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	// Only the latest value is kept for the goroutine below, Subscribe
+	// callbacks must not block.
+	values := make(chan T, 1)
+	var mu sync.Mutex
+	unsubscribe := s.Subscribe(func(val T) {
+		mu.Lock()
+		defer mu.Unlock()
+		select {
+		case <-values:
+		default:
+		}
+		values <- val
+	})
+
+	ctx, cancel := context.WithCancel(ctx)
+	bt.running.Go(func() {
+		defer cancel()
+		defer unsubscribe()
+
+		// Unblock Push when the tree shuts down.
+		go func() {
+			select {
+			case <-bt.stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		var (
+			last     T
+			sent     bool
+			pending  T
+			debounce *time.Timer
+			fire     <-chan time.Time
+		)
+		enqueue := func(val T) {
+			if sent && config.equal != nil && config.equal(last, val) {
+				return
+			}
+			last, sent = val, true
+			if err := bt.events.Push(ctx, evt(val)); err != nil && !errors.Is(err, context.Canceled) {
+				internal.Logger.Error("Could not queue event for watched state", "err", err)
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case val := <-values:
+				if config.debounce <= 0 {
+					enqueue(val)
+					continue
+				}
+				pending = val
+				if debounce == nil {
+					debounce = time.NewTimer(config.debounce)
+					defer debounce.Stop()
+				} else {
+					debounce.Reset(config.debounce)
+				}
+				fire = debounce.C
+			case <-fire:
+				fire = nil
+				enqueue(pending)
+			}
+		}
+	})
+
+	return cancel
+}